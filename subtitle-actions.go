@@ -1,8 +1,6 @@
 package subtitle
 
 import (
-	"fmt"
-	"regexp"
 	"strings"
 )
 
@@ -15,10 +13,15 @@ import (
 // Then, both linesets are processed with SplitTranslatedLineSetIntoLines.
 // Input: lineset to move from and number of lines
 func (this *SubtitleSRT) MoveLinesFromLineSetToPrev(lsFrom, n int) {
+	defer this.beginEdit("MoveLinesFromLineSetToPrev", lsFrom, n)(nil)
 	// Verify that lsFrom is a valid lineset (1 .. #lineSet-1)
 	if lsFrom <= 0 || lsFrom >= len(this.lineSet) || n <= 0 {
 		return
 	}
+	// Locked LineSets cannot change
+	if this.lineSet[lsFrom].Locked || this.lineSet[lsFrom-1].Locked {
+		return
+	}
 	// cap n to the number of lines
 	if n > (this.lineSet[lsFrom].LastLine - this.lineSet[lsFrom].InitLine + 1) {
 		n = this.lineSet[lsFrom].LastLine - this.lineSet[lsFrom].InitLine + 1
@@ -34,7 +37,7 @@ func (this *SubtitleSRT) MoveLinesFromLineSetToPrev(lsFrom, n int) {
 	// remove the text from the top of lineSet[lsFrom].translatedText
 	this.translatedSet[lsFrom] = strings.TrimSpace(strings.TrimPrefix(this.translatedSet[lsFrom], toBeRemoved))
 	// add the text to the bottom of lineSet[lsFrom-1].translatedText
-	this.translatedSet[lsTo] = (this.translatedSet[lsTo] + " " + toBeRemoved)
+	this.translatedSet[lsTo] = joinStrings(this.translatedSet[lsTo], toBeRemoved)
 
 	// Split the translation of the two affected lineSet into lines
 	this.splitTranslatedLineSetIntoLines(lsFrom)
@@ -46,10 +49,15 @@ func (this *SubtitleSRT) MoveLinesFromLineSetToPrev(lsFrom, n int) {
 // Then, both linesets are processed with SplitTranslatedLineSetIntoLines.
 // Input: lineset to move from and number of words
 func (this *SubtitleSRT) MoveWordsFromLineSetToPrev(lsFrom, n int) {
+	defer this.beginEdit("MoveWordsFromLineSetToPrev", lsFrom, n)(nil)
 	// Verify that lsFrom is a valid lineset (0 .. #lineSet)
 	if lsFrom <= 0 || lsFrom > len(this.lineSet)-1 || n <= 0 {
 		return
 	}
+	// Locked LineSets cannot change
+	if this.lineSet[lsFrom].Locked || this.lineSet[lsFrom-1].Locked {
+		return
+	}
 	// cap n to the number of words
 	maxWords := this.CountTranslatedWordsInLineSet(lsFrom)
 	if n > maxWords {
@@ -57,15 +65,14 @@ func (this *SubtitleSRT) MoveWordsFromLineSetToPrev(lsFrom, n int) {
 	}
 	// Prepare local variables
 	lsTo := lsFrom - 1
-	rs := fmt.Sprintf(`^(\S+\s*){%d}`, n)
-	loc := regexp.MustCompile((rs)).FindStringIndex(this.translatedSet[lsFrom])
-	if loc == nil {
+	words, rest := splitFirstWords(this.translatedSet[lsFrom], n)
+	if words == "" {
 		return
 	}
 
 	// Remove the first n words from lsFrom and add it to lsTo
-	this.translatedSet[lsTo] = this.translatedSet[lsTo] + " " + strings.TrimSpace(this.translatedSet[lsFrom][loc[0]:loc[1]])
-	this.translatedSet[lsFrom] = this.translatedSet[lsFrom][loc[1]:]
+	this.translatedSet[lsTo] = joinStrings(this.translatedSet[lsTo], words)
+	this.translatedSet[lsFrom] = rest
 
 	// Split the translation of the two affected lineSet into lines
 	this.splitTranslatedLineSetIntoLines(lsFrom)
@@ -77,10 +84,15 @@ func (this *SubtitleSRT) MoveWordsFromLineSetToPrev(lsFrom, n int) {
 // Then, both linesets are processed with SplitTranslatedLineSetIntoLines.
 // Input: lineset to move from and number of lines
 func (this *SubtitleSRT) MoveLinesFromLineSetToNext(lsFrom, n int) {
+	defer this.beginEdit("MoveLinesFromLineSetToNext", lsFrom, n)(nil)
 	// Verify that lsFrom is a valid lineset (1 .. #lineSet-1)
 	if lsFrom < 0 || lsFrom >= (len(this.lineSet)-1) || n <= 0 {
 		return
 	}
+	// Locked LineSets cannot change
+	if this.lineSet[lsFrom].Locked || this.lineSet[lsFrom+1].Locked {
+		return
+	}
 	// cap n to the number of lines
 	if n > (this.lineSet[lsFrom].LastLine - this.lineSet[lsFrom].InitLine + 1) {
 		n = this.lineSet[lsFrom].LastLine - this.lineSet[lsFrom].InitLine + 1
@@ -96,7 +108,7 @@ func (this *SubtitleSRT) MoveLinesFromLineSetToNext(lsFrom, n int) {
 	// remove the text from the bottom of lineSet[lsFrom].translatedText
 	this.translatedSet[lsFrom] = strings.TrimSpace(strings.TrimSuffix(this.translatedSet[lsFrom], toBeRemoved))
 	// add the text to the top of lineSet[lsFrom-1].translatedText
-	this.translatedSet[lsTo] = (toBeRemoved + " " + this.translatedSet[lsTo])
+	this.translatedSet[lsTo] = joinStrings(toBeRemoved, this.translatedSet[lsTo])
 
 	// Split the translation of the two affected lineSet into lines
 	this.splitTranslatedLineSetIntoLines(lsFrom)
@@ -108,10 +120,15 @@ func (this *SubtitleSRT) MoveLinesFromLineSetToNext(lsFrom, n int) {
 // Then, both linesets are processed with SplitTranslatedLineSetIntoLines.
 // Input: lineset to move from and number of words
 func (this *SubtitleSRT) MoveWordsFromLineSetToNext(lsFrom, n int) {
+	defer this.beginEdit("MoveWordsFromLineSetToNext", lsFrom, n)(nil)
 	// Verify that lsFrom is a valid lineset (0 .. #lineSet)
 	if lsFrom < 0 || lsFrom >= len(this.lineSet)-1 || n <= 0 {
 		return
 	}
+	// Locked LineSets cannot change
+	if this.lineSet[lsFrom].Locked || this.lineSet[lsFrom+1].Locked {
+		return
+	}
 	// cap n to the number of words
 	maxWords := this.CountTranslatedWordsInLineSet(lsFrom)
 	if n > maxWords {
@@ -119,15 +136,14 @@ func (this *SubtitleSRT) MoveWordsFromLineSetToNext(lsFrom, n int) {
 	}
 	// Prepare local variables
 	lsTo := lsFrom + 1
-	rs := fmt.Sprintf(`(\s*\S+){%d}$`, n)
-	loc := regexp.MustCompile((rs)).FindStringIndex(this.translatedSet[lsFrom])
-	if loc == nil {
+	rest, words := splitLastWords(this.translatedSet[lsFrom], n)
+	if words == "" {
 		return
 	}
 
 	// Remove the last n words from lsFrom and add it to beginning of lsTo
-	this.translatedSet[lsTo] = strings.TrimSpace(this.translatedSet[lsFrom][loc[0]:loc[1]] + " " + this.translatedSet[lsTo])
-	this.translatedSet[lsFrom] = this.translatedSet[lsFrom][:loc[0]]
+	this.translatedSet[lsTo] = strings.TrimSpace(joinStrings(words, this.translatedSet[lsTo]))
+	this.translatedSet[lsFrom] = rest
 
 	// Split the translation of the two affected lineSet into lines
 	this.splitTranslatedLineSetIntoLines(lsFrom)
@@ -140,6 +156,11 @@ func (this *SubtitleSRT) MoveWordsFromLineSetToNext(lsFrom, n int) {
 // Affected lineset is *not* processed with SplitTranslatedLineSetIntoLines.
 // Input: line number to move from
 func (this *SubtitleSRT) MoveWordFromLineToPrev(lineFrom int) {
+	defer this.beginEdit("MoveWordFromLineToPrev", lineFrom)(nil)
+	// A locked LineSet cannot change
+	if this.IsLineSetLocked(this.WhatLineSetIsLine(lineFrom)) {
+		return
+	}
 	// Verify that lineFrom is the first one of a lineset
 	if this.IsFirstLineOfLineSet(lineFrom) {
 		// if so, fallback to MoveWordsFromLinesetToPrev(1)
@@ -148,17 +169,16 @@ func (this *SubtitleSRT) MoveWordFromLineToPrev(lineFrom int) {
 	}
 
 	// Find the first word of the line
-	rs := `^(\S+\s*)`
-	loc := regexp.MustCompile((rs)).FindStringIndex(this.translatedLine[lineFrom])
-	if loc == nil {
+	word, rest := splitFirstWords(this.translatedLine[lineFrom], 1)
+	if word == "" {
 		return
 	}
 	lineTo := lineFrom - 1
 
 	// Add the first word of lineFrom to lineTo
-	this.translatedLine[lineTo] = strings.TrimSpace(this.translatedLine[lineTo] + " " + this.translatedLine[lineFrom][loc[0]:loc[1]])
+	this.translatedLine[lineTo] = strings.TrimSpace(joinStrings(this.translatedLine[lineTo], word))
 	// Remove it from lineFrom
-	this.translatedLine[lineFrom] = this.translatedLine[lineFrom][loc[1]:]
+	this.translatedLine[lineFrom] = rest
 }
 
 // MoveWordFromLineToNext moves 1 translated words(s)
@@ -167,6 +187,11 @@ func (this *SubtitleSRT) MoveWordFromLineToPrev(lineFrom int) {
 // Affected lineset is *not* processed with SplitTranslatedLineSetIntoLines.
 // Input: line number to move from
 func (this *SubtitleSRT) MoveWordFromLineToNext(lineFrom int) {
+	defer this.beginEdit("MoveWordFromLineToNext", lineFrom)(nil)
+	// A locked LineSet cannot change
+	if this.IsLineSetLocked(this.WhatLineSetIsLine(lineFrom)) {
+		return
+	}
 	// Verify that lineFrom is the last one of a lineset
 	if this.IsLastLineOfLineSet(lineFrom) {
 		// if so, fallback to MoveWordsFromLinesetToNext(1)
@@ -175,17 +200,16 @@ func (this *SubtitleSRT) MoveWordFromLineToNext(lineFrom int) {
 	}
 
 	// Find the last word of the line
-	rs := `(\s*\S+)$`
-	loc := regexp.MustCompile((rs)).FindStringIndex(this.translatedLine[lineFrom])
-	if loc == nil {
+	rest, word := splitLastWords(this.translatedLine[lineFrom], 1)
+	if word == "" {
 		return
 	}
 	lineTo := lineFrom + 1
 
 	// Add the last word of lineFrom to lineTo
-	this.translatedLine[lineTo] = strings.TrimSpace(this.translatedLine[lineFrom][loc[0]:loc[1]] + " " + this.translatedLine[lineTo])
+	this.translatedLine[lineTo] = strings.TrimSpace(joinStrings(word, this.translatedLine[lineTo]))
 	// Remove it from lineFrom
-	this.translatedLine[lineFrom] = this.translatedLine[lineFrom][:loc[0]]
+	this.translatedLine[lineFrom] = rest
 }
 
 // SplitLineSetByLine splits a lineset in two by a specified line.
@@ -193,8 +217,9 @@ func (this *SubtitleSRT) MoveWordFromLineToNext(lineFrom int) {
 // Then, both linesets are processed with SplitTranslatedLineSetIntoLines.
 // It takes the lineset to be split and the line to break at.
 func (this *SubtitleSRT) SplitLineSetByLine(ls, breakLine int) {
-	// Verify that lsFrom is a valid lineset (0 .. #lineSet)
-	if ls < 0 || ls >= len(this.lineSet) {
+	defer this.beginEdit("SplitLineSetByLine", ls, breakLine)(nil)
+	// Verify that lsFrom is a valid lineset (0 .. #lineSet), not locked
+	if ls < 0 || ls >= len(this.lineSet) || this.lineSet[ls].Locked {
 		return
 	}
 	// Verify that the lineSet has more than one line, and breakline is in it
@@ -205,7 +230,7 @@ func (this *SubtitleSRT) SplitLineSetByLine(ls, breakLine int) {
 		return
 	}
 	// add a new lineSet and translatedSet
-	this.lineSet = append(this.lineSet, LineSet{0, 0})
+	this.lineSet = append(this.lineSet, LineSet{InitLine: 0, LastLine: 0})
 	this.translatedSet = append(this.translatedSet, "")
 	// Move lineSets and translatedSet +1
 	copy(this.lineSet[ls+1:], this.lineSet[ls:])
@@ -224,17 +249,24 @@ func (this *SubtitleSRT) SplitLineSetByLine(ls, breakLine int) {
 }
 
 // MergeLineSet merges a LineSet with the previous one
-// into a single lineSet
+// into a single lineSet, with the lower status and the comments of both
 // Resultant lineset is *not* processed with SplitTranslatedLineSetIntoLines.
 // It takes the lineset to merge.
 func (this *SubtitleSRT) MergeLineSetWithPrev(ls int) {
+	defer this.beginEdit("MergeLineSetWithPrev", ls)(nil)
 	// Verify that the situation is legal
 	if ls <= 0 || ls > len(this.lineSet)-1 {
 		// (****) raise error
 		return
 	}
+	// Locked LineSets cannot change
+	if this.lineSet[ls-1].Locked || this.lineSet[ls].Locked {
+		return
+	}
 	// Last line of LineSet ls-1 now is the last line of ls
 	this.lineSet[ls-1].LastLine = this.lineSet[ls].LastLine
+	this.lineSet[ls-1].Exact = this.lineSet[ls-1].Exact && this.lineSet[ls].Exact
+	this.lineSet[ls-1].mergeReview(this.lineSet[ls])
 	// The translated text of the joint is the joint of the two translated texts
 	this.translatedSet[ls-1] = joinStrings(this.translatedSet[ls-1 : ls+1]...)
 	// Copy all the subsequent linesets to -1
@@ -244,17 +276,24 @@ func (this *SubtitleSRT) MergeLineSetWithPrev(ls int) {
 }
 
 // MergeLineSetWithNext merges a LineSet with the next one
-// into a single lineSet
+// into a single lineSet, with the lower status and the comments of both
 // Resultant lineset is *not* processed with SplitTranslatedLineSetIntoLines.
 // It takes the lineset to merge.
 func (this *SubtitleSRT) MergeLineSetWithNext(ls int) {
+	defer this.beginEdit("MergeLineSetWithNext", ls)(nil)
 	// Verify that the situation is legal
 	if ls < 0 || ls >= len(this.lineSet)-1 {
 		// (****) raise error
 		return
 	}
+	// Locked LineSets cannot change
+	if this.lineSet[ls].Locked || this.lineSet[ls+1].Locked {
+		return
+	}
 	// Last line of LineSet ls now is the last line of ls+1
 	this.lineSet[ls].LastLine = this.lineSet[ls+1].LastLine
+	this.lineSet[ls].Exact = this.lineSet[ls].Exact && this.lineSet[ls+1].Exact
+	this.lineSet[ls].mergeReview(this.lineSet[ls+1])
 	// The translated text of the joint is the joint of the two translated texts
 	this.translatedSet[ls] = joinStrings(this.translatedSet[ls : ls+2]...)
 	// Copy all the subsequent linesets to -1
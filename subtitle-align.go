@@ -0,0 +1,399 @@
+package subtitle
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// ------------------------------------------------------
+// Strategies to split the translated text into LineSets
+// ------------------------------------------------------
+
+// An AlignStrategy defines how the translated text is split into LineSets
+type AlignStrategy int
+
+const (
+	// ExactMatchAlignment detects LineSets by looking for original lines
+	// copied verbatim into the translation (minmatch exact matching)
+	ExactMatchAlignment AlignStrategy = iota
+	// StatisticalAlignment detects LineSets by aligning original and
+	// translated sentences by their length (Gale-Church)
+	StatisticalAlignment
+)
+
+// AlignOptions configures how the translated text is split into LineSets
+// The zero value keeps the default behaviour of SetTranslatedText
+type AlignOptions struct {
+	// MinMatch is the min length of a line to be matched exactly in the
+	// middle of the translation (0 means the default, 15)
+	MinMatch int
+	// BracketPattern is the regexp of a group between brackets ([Music]).
+	// An original line containing it matches the next group in the translation
+	// ("" means the default, `\[.+\]` in the original and `\[([^]]+)\]` in the translation)
+	BracketPattern string
+	// EmptyLineToken is the text representing an empty original line
+	// in the translation ("" means the default, "[]")
+	EmptyLineToken string
+	// IgnoreCase matches the original lines regardless of case
+	IgnoreCase bool
+	// MiniLinesMidText allows lines shorter than MinMatch to match
+	// in the middle of the translated text
+	MiniLinesMidText bool
+}
+
+// Default values of AlignOptions
+const (
+	defaultMinMatch       = 15
+	defaultEmptyLineToken = "[]"
+)
+
+// Default regular expressions for lines between brackets
+var (
+	defaultBracketLineRegexp  = regexp.MustCompile(`\[.+\]`)
+	defaultBracketGroupRegexp = regexp.MustCompile(`\[([^]]+)\]`)
+)
+
+// Validate returns an error if the options cannot be used
+func (o AlignOptions) Validate() error {
+	if o.MinMatch < 0 {
+		return fmt.Errorf("subtitle: invalid MinMatch %d", o.MinMatch)
+	}
+	if o.BracketPattern != "" {
+		if _, err := regexp.Compile(o.BracketPattern); err != nil {
+			return fmt.Errorf("subtitle: invalid BracketPattern: %v", err)
+		}
+	}
+	if strings.IndexFunc(o.EmptyLineToken, unicode.IsSpace) >= 0 {
+		return fmt.Errorf("subtitle: EmptyLineToken %q has spaces", o.EmptyLineToken)
+	}
+	return nil
+}
+
+// minMatch returns MinMatch or its default
+func (o AlignOptions) minMatch() int {
+	if o.MinMatch == 0 {
+		return defaultMinMatch
+	}
+	return o.MinMatch
+}
+
+// emptyLineToken returns EmptyLineToken or its default
+func (o AlignOptions) emptyLineToken() string {
+	if o.EmptyLineToken == "" {
+		return defaultEmptyLineToken
+	}
+	return o.EmptyLineToken
+}
+
+// bracketRegexps returns the regexps to detect an original line between
+// brackets, and to find a group between brackets in the translation
+func (o AlignOptions) bracketRegexps() (*regexp.Regexp, *regexp.Regexp) {
+	if o.BracketPattern == "" {
+		return defaultBracketLineRegexp, defaultBracketGroupRegexp
+	}
+	re := regexp.MustCompile(o.BracketPattern)
+	return re, re
+}
+
+// standaloneRegexp returns the regexp of the groups that are sentences
+// by themselves: groups between brackets and the empty line token
+func (o AlignOptions) standaloneRegexp() *regexp.Regexp {
+	_, group := o.bracketRegexps()
+	if o.emptyLineToken() == defaultEmptyLineToken && o.BracketPattern == "" {
+		return bracketGroupRegexp
+	}
+	return regexp.MustCompile(`(?:` + group.String() + `)|` + regexp.QuoteMeta(o.emptyLineToken()))
+}
+
+// concatWithSpace is ConcatWithSpace with the empty line token instead of [],
+// the token is never kept in the result
+func (o AlignOptions) concatWithSpace(str1, str2 string) string {
+	token := o.emptyLineToken()
+	if str1 == token {
+		str1 = ""
+	}
+	if str2 == token {
+		str2 = ""
+	}
+	return ConcatWithSpace(str1, str2)
+}
+
+// Gale-Church parameters:
+//   - variance of the translated length per original char
+//   - prior probability of each kind of bead (orig:trans sentences)
+const galeChurchVariance = 6.8
+
+var galeChurchBeads = []struct {
+	nOrig, nTrans int
+	prior         float64
+}{
+	{1, 1, 0.89},
+	{1, 0, 0.0099 / 2},
+	{0, 1, 0.0099 / 2},
+	{2, 1, 0.089 / 2},
+	{1, 2, 0.089 / 2},
+}
+
+// An alignUnit is an original sentence: a run of lines that ends
+// with a sentence terminator, an empty line or a line between brackets
+type alignUnit struct {
+	text     string
+	initLine int
+	lastLine int
+}
+
+// Regular expressions used to split sentences
+var (
+	sentenceEndRegexp  = regexp.MustCompile(`[.!?…]+["'»”’)]*(\s+|$)`)
+	lineEndRegexp      = regexp.MustCompile(`[.!?…]+["'»”’)]*$`)
+	bracketGroupRegexp = regexp.MustCompile(`\[[^\]]*\]`)
+)
+
+// Split the translated text into LineSets with the selected strategy
+func (this *SubtitleSRT) detectLineSets() {
+	this.lineSet = nil
+	this.translatedSet = nil
+	switch this.alignStrategy {
+	case StatisticalAlignment:
+		this.alignTranslatedTextByLength()
+	default:
+		this.splitTranslatedTextIntoLineSets()
+	}
+}
+
+// Split what is in translatedText into line sets by aligning the
+// original sentences with the translated sentences (Gale-Church)
+func (this *SubtitleSRT) alignTranslatedTextByLength() {
+
+	// High level process:
+	//   1. Group the original lines into sentences (alignUnit:s)
+	//   2. Split the translated text into sentences
+	//   3. Find the cheapest sequence of beads (1:1, 1:0, 0:1, 2:1, 1:2)
+	//      given the length of the sentences
+	//   4. Each bead with original sentences is a LineSet, translated sentences
+	//      without an original one (0:1) are appended to the previous LineSet
+	//   5. LineSets with only empty lines are merged into the previous one
+	//   6. The translation left without a LineSet goes to the last one
+	// The empty line token is never kept in the translation of a LineSet
+
+	opts := this.alignOptions
+	units := this.originalAlignUnits()
+	sentences := splitIntoSentences(this.translatedText, opts.standaloneRegexp())
+
+	origLen := make([]int, len(units))
+	for i, u := range units {
+		origLen[i] = countChars(u.text)
+	}
+	transLen := make([]int, len(sentences))
+	for i, s := range sentences {
+		transLen[i] = countChars(s)
+	}
+
+	pending := ""
+	nTrans := 0
+	for _, b := range galeChurchAlign(origLen, transLen) {
+		text := ""
+		for _, s := range sentences[nTrans : nTrans+b[1]] {
+			text = opts.concatWithSpace(text, s)
+		}
+		nTrans += b[1]
+		if b[0] == 0 {
+			// Translation without original: goes to the previous LineSet
+			if len(this.lineSet) == 0 {
+				pending = opts.concatWithSpace(pending, text)
+			} else {
+				last := len(this.translatedSet) - 1
+				this.translatedSet[last] = opts.concatWithSpace(this.translatedSet[last], text)
+			}
+			continue
+		}
+		first, last := units[0], units[b[0]-1]
+		units = units[b[0]:]
+		text = opts.concatWithSpace(pending, text)
+		pending = ""
+		if len(this.lineSet) > 0 && this.areEmptyLines(first.initLine, last.lastLine) {
+			// Only empty lines: extend the previous LineSet
+			this.lineSet[len(this.lineSet)-1].LastLine = last.lastLine
+			prev := len(this.translatedSet) - 1
+			this.translatedSet[prev] = opts.concatWithSpace(this.translatedSet[prev], text)
+			continue
+		}
+		this.lineSet = append(this.lineSet, LineSet{InitLine: first.initLine, LastLine: last.lastLine})
+		this.translatedSet = append(this.translatedSet, text)
+	}
+
+	// Without alignable units, the translation is a LineSet of all the lines
+	for _, s := range sentences[nTrans:] {
+		pending = opts.concatWithSpace(pending, s)
+	}
+	switch {
+	case len(this.lineSet) > 0:
+		last := len(this.translatedSet) - 1
+		this.translatedSet[last] = opts.concatWithSpace(this.translatedSet[last], pending)
+	case len(this.originalLine) > 0:
+		this.lineSet = []LineSet{{InitLine: 0, LastLine: len(this.originalLine) - 1}}
+		this.translatedSet = []string{pending}
+	}
+}
+
+// Group the original lines into sentences
+// Empty lines and lines with a group between brackets are sentences by themselves
+func (this *SubtitleSRT) originalAlignUnits() []alignUnit {
+	var units []alignUnit
+	current := alignUnit{initLine: -1}
+	token := this.alignOptions.emptyLineToken()
+	bracketLine, _ := this.alignOptions.bracketRegexps()
+
+	flush := func() {
+		if current.initLine >= 0 {
+			units = append(units, current)
+		}
+		current = alignUnit{initLine: -1}
+	}
+
+	for i, theLine := range this.originalLine {
+		if theLine == "" || bracketLine.MatchString(theLine) {
+			flush()
+			if theLine == "" {
+				theLine = token
+			}
+			units = append(units, alignUnit{theLine, i, i})
+			continue
+		}
+		if current.initLine < 0 {
+			current.initLine = i
+		}
+		current.lastLine = i
+		current.text = this.alignOptions.concatWithSpace(current.text, theLine)
+		if lineEndRegexp.MatchString(theLine) {
+			flush()
+		}
+	}
+	flush()
+	return units
+}
+
+// areEmptyLines returns true if all original lines from init to last are empty
+func (this *SubtitleSRT) areEmptyLines(init, last int) bool {
+	for i := init; i <= last; i++ {
+		if this.originalLine[i] != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// Split a text into sentences
+// Groups matching standalone ([Music], []) are sentences by themselves
+func splitIntoSentences(text string, standalone *regexp.Regexp) []string {
+	var sentences []string
+
+	addChunk := func(chunk string) {
+		chunk = strings.TrimSpace(chunk)
+		for chunk != "" {
+			loc := sentenceEndRegexp.FindStringIndex(chunk)
+			if loc == nil {
+				sentences = append(sentences, chunk)
+				return
+			}
+			sentences = append(sentences, strings.TrimSpace(chunk[:loc[1]]))
+			chunk = strings.TrimSpace(chunk[loc[1]:])
+		}
+	}
+
+	last := 0
+	for _, loc := range standalone.FindAllStringIndex(text, -1) {
+		addChunk(text[last:loc[0]])
+		sentences = append(sentences, text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	addChunk(text[last:])
+	return sentences
+}
+
+// galeChurchAlign finds the sequence of beads with minimum cost
+// given the lengths of the original and the translated sentences.
+// Each bead is returned as [number of original, number of translated] sentences
+func galeChurchAlign(origLen, transLen []int) [][2]int {
+	nOrig, nTrans := len(origLen), len(transLen)
+
+	// c is the expected number of translated chars per original char
+	totalOrig, totalTrans := 0, 0
+	for _, l := range origLen {
+		totalOrig += l
+	}
+	for _, l := range transLen {
+		totalTrans += l
+	}
+	c := 1.0
+	if totalOrig > 0 && totalTrans > 0 {
+		c = float64(totalTrans) / float64(totalOrig)
+	}
+
+	// cost[i][j] is the minimum cost to align i original and j translated sentences
+	// bead[i][j] is the index of the last bead in that alignment
+	cost := make([][]float64, nOrig+1)
+	bead := make([][]int, nOrig+1)
+	for i := range cost {
+		cost[i] = make([]float64, nTrans+1)
+		bead[i] = make([]int, nTrans+1)
+		for j := range cost[i] {
+			cost[i][j] = math.Inf(1)
+		}
+	}
+	cost[0][0] = 0
+
+	for i := 0; i <= nOrig; i++ {
+		for j := 0; j <= nTrans; j++ {
+			for k, b := range galeChurchBeads {
+				if i < b.nOrig || j < b.nTrans || math.IsInf(cost[i-b.nOrig][j-b.nTrans], 1) {
+					continue
+				}
+				l1, l2 := 0, 0
+				for _, l := range origLen[i-b.nOrig : i] {
+					l1 += l
+				}
+				for _, l := range transLen[j-b.nTrans : j] {
+					l2 += l
+				}
+				d := cost[i-b.nOrig][j-b.nTrans] + galeChurchCost(l1, l2, c, b.prior)
+				if d < cost[i][j] {
+					cost[i][j] = d
+					bead[i][j] = k
+				}
+			}
+		}
+	}
+
+	// Backtrack from the end
+	var beads [][2]int
+	for i, j := nOrig, nTrans; i > 0 || j > 0; {
+		b := galeChurchBeads[bead[i][j]]
+		beads = append(beads, [2]int{b.nOrig, b.nTrans})
+		i -= b.nOrig
+		j -= b.nTrans
+	}
+	for l, r := 0, len(beads)-1; l < r; l, r = l+1, r-1 {
+		beads[l], beads[r] = beads[r], beads[l]
+	}
+	return beads
+}
+
+// galeChurchCost returns -log(P(bead)*P(l2|l1)) for a bead of l1 original
+// and l2 translated chars, where c is the expected translated/original ratio
+func galeChurchCost(l1, l2 int, c, prior float64) float64 {
+	if l1 == 0 && l2 == 0 {
+		return 0
+	}
+	mean := (float64(l1) + float64(l2)/c) / 2
+	z := math.Abs(c*float64(l1)-float64(l2)) / math.Sqrt(galeChurchVariance*mean)
+	// Two tailed probability of a deviation of z in a normal distribution
+	pd := math.Erfc(z / math.Sqrt2)
+	if pd < 1e-300 {
+		pd = 1e-300
+	}
+	return -math.Log(prior) - math.Log(pd)
+}
@@ -0,0 +1,318 @@
+package subtitle
+
+import (
+	"strings"
+	"testing"
+)
+
+// A small SRT whose translation is a real (not verbatim) translation
+const alignTestSrt = `1
+00:00:01,000 --> 00:00:03,000
+Good morning, everybody.
+Thank you all for coming today.
+
+2
+00:00:03,500 --> 00:00:06,000
+We are going to talk about
+the history of the city.
+
+3
+00:00:06,500 --> 00:00:07,000
+[Music]
+
+4
+00:00:07,500 --> 00:00:08,000
+
+5
+00:00:08,500 --> 00:00:11,000
+It was founded two thousand years ago
+by a small group of fishermen.
+
+6
+00:00:11,500 --> 00:00:13,000
+Let's begin.
+`
+
+const alignTestTxt = `Buenos días a todos. Gracias a todos por venir hoy. ` +
+	`Vamos a hablar de la historia de la ciudad. [Música] [] ` +
+	`Fue fundada hace dos mil años por un pequeño grupo de pescadores. Empecemos.`
+
+// newTestSubtitle loads an SRT and its translation with the given strategy
+func newTestSubtitle(srt, txt string, strategy AlignStrategy) SubtitleSRT {
+	var subt SubtitleSRT
+	subt.SetOriginalSrt(strings.NewReader(srt))
+	subt.SetAlignStrategy(strategy)
+	subt.SetTranslatedText(txt)
+	return subt
+}
+
+func TestSplitIntoSentences(t *testing.T) {
+	have := splitIntoSentences(`Hola. ¿Qué tal? [Música] [] Bien... gracias "amigo." Adiós`, bracketGroupRegexp)
+	want := []string{"Hola.", "¿Qué tal?", "[Música]", "[]", "Bien...", `gracias "amigo."`, "Adiós"}
+	if strings.Join(have, "|") != strings.Join(want, "|") {
+		t.Fatalf("splitIntoSentences(): want %q have %q", want, have)
+	}
+}
+
+func TestExactMatchCollapsesRealTranslation(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, ExactMatchAlignment)
+	// Nothing is copied verbatim: all the text before [Music] is one LineSet
+	if ls := subt.GetLineSets()[0]; !ls.IsEqual(LineSet{InitLine: 0, LastLine: 3}) {
+		t.Fatalf("ExactMatchAlignment: LineSet 0: want {0 3} have %v", ls)
+	}
+}
+
+func TestStatisticalAlignment(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, StatisticalAlignment)
+
+	want := []LineSet{
+		{InitLine: 0, LastLine: 0}, {InitLine: 1, LastLine: 1}, {InitLine: 2, LastLine: 3},
+		{InitLine: 4, LastLine: 5}, {InitLine: 6, LastLine: 7}, {InitLine: 8, LastLine: 8},
+	}
+	have := subt.GetLineSets()
+	if len(have) != len(want) {
+		t.Fatalf("StatisticalAlignment: want %v have %v", want, have)
+	}
+	for i := range want {
+		if !have[i].IsEqual(want[i]) {
+			t.Fatalf("StatisticalAlignment: LineSet %d: want %v have %v", i, want[i], have[i])
+		}
+	}
+	text, _ := subt.GetTranslatedTextOfLineSet(4)
+	if text != "Fue fundada hace dos mil años por un pequeño grupo de pescadores." {
+		t.Fatalf("StatisticalAlignment: LineSet 4: unexpected text %q", text)
+	}
+	if !subt.IsTranslationConsistent() {
+		t.Fatal("StatisticalAlignment: Translation is not consistent")
+	}
+}
+
+func TestStatisticalAlignmentExtraSentence(t *testing.T) {
+	// A translated sentence without original is kept in the previous LineSet
+	txt := strings.Replace(alignTestTxt, "Empecemos.", "Empecemos. Ahora mismo.", 1)
+	subt := newTestSubtitle(alignTestSrt, txt, StatisticalAlignment)
+
+	last := subt.CountLineSets() - 1
+	text, _ := subt.GetTranslatedTextOfLineSet(last)
+	if !strings.HasSuffix(text, "Ahora mismo.") {
+		t.Fatalf("StatisticalAlignment: last LineSet lost the extra sentence: %q", text)
+	}
+	if !subt.IsTranslationConsistent() {
+		t.Fatal("StatisticalAlignment: Translation is not consistent")
+	}
+}
+
+func TestStatisticalAlignmentWithoutUnits(t *testing.T) {
+	// Only empty lines: the translation is a LineSet of all the lines
+	srt := "1\n00:00:01,000 --> 00:00:02,000\n\n\n2\n00:00:02,500 --> 00:00:03,000\n\n"
+	subt := newTestSubtitle(srt, "Hola. Adiós.", StatisticalAlignment)
+	if subt.CountLineSets() != 1 || subt.lineSet[0].LastLine != 1 || subt.translatedSet[0] != "Hola. Adiós." {
+		t.Fatalf("StatisticalAlignment: unexpected LineSets %+v %q", subt.lineSet, subt.translatedSet)
+	}
+	if !subt.IsTranslationConsistent() {
+		t.Fatal("StatisticalAlignment: Translation is not consistent")
+	}
+}
+
+func TestStatisticalAlignmentEmptyLineToken(t *testing.T) {
+	srt := "1\n00:00:01,000 --> 00:00:02,000\nHello.\n\n2\n00:00:02,000 --> 00:00:03,000\n\n\n" +
+		"3\n00:00:03,000 --> 00:00:04,000\n[Music]\n"
+	for _, txt := range []string{"Hola. []", "[] Hola.", "Hola. [] [] [Música]", "[][] Hola.[]"} {
+		subt := newTestSubtitle(srt, txt, StatisticalAlignment)
+		for ls, text := range subt.translatedSet {
+			if strings.Contains(text, "[]") {
+				t.Fatalf("StatisticalAlignment(%q): LineSet %d keeps the empty line token: %q", txt, ls, text)
+			}
+		}
+		if !subt.IsTranslationConsistent() {
+			t.Fatalf("StatisticalAlignment(%q): Translation is not consistent %q", txt, subt.translatedSet)
+		}
+	}
+}
+
+// Three one-line blocks, the middle one is copied verbatim or
+// is a special line depending on the test
+func alignOptionsTestSrt(middle string) string {
+	return "1\n00:00:01,000 --> 00:00:02,000\nGood morning\n\n" +
+		"2\n00:00:02,500 --> 00:00:03,000\n" + middle + "\n\n" +
+		"3\n00:00:03,500 --> 00:00:04,000\nGoodbye\n"
+}
+
+// newTestSubtitleWithOptions loads an SRT and its translation with AlignOptions
+func newTestSubtitleWithOptions(t *testing.T, srt, txt string, opts AlignOptions) SubtitleSRT {
+	var subt SubtitleSRT
+	subt.SetOriginalSrt(strings.NewReader(srt))
+	if err := subt.SetTranslatedTextWithOptions(txt, opts); err != nil {
+		t.Fatalf("SetTranslatedTextWithOptions(%+v): %v", opts, err)
+	}
+	if !subt.IsTranslationConsistent() {
+		t.Fatalf("SetTranslatedTextWithOptions(%+v): Translation is not consistent", opts)
+	}
+	return subt
+}
+
+func TestAlignOptionsMinMatch(t *testing.T) {
+	srt := alignOptionsTestSrt("Hola amigo")
+	txt := "Buenos días Hola amigo Adiós"
+
+	subt := newTestSubtitleWithOptions(t, srt, txt, AlignOptions{})
+	if subt.CountLineSets() != 1 {
+		t.Fatalf("MinMatch default: want 1 LineSet, have %d", subt.CountLineSets())
+	}
+	subt = newTestSubtitleWithOptions(t, srt, txt, AlignOptions{MinMatch: 5})
+	if subt.CountLineSets() != 3 {
+		t.Fatalf("MinMatch 5: want 3 LineSets, have %d", subt.CountLineSets())
+	}
+	if subt.GetTranslatedLines()[1] != "Hola amigo" {
+		t.Fatalf("MinMatch 5: Line 1: want 'Hola amigo' have '%s'", subt.GetTranslatedLines()[1])
+	}
+}
+
+func TestAlignOptionsMiniLinesMidText(t *testing.T) {
+	srt := alignOptionsTestSrt("Hola amigo")
+	subt := newTestSubtitleWithOptions(t, srt, "Buenos días Hola amigo Adiós", AlignOptions{MiniLinesMidText: true})
+	if subt.CountLineSets() != 3 {
+		t.Fatalf("MiniLinesMidText: want 3 LineSets, have %d", subt.CountLineSets())
+	}
+}
+
+func TestAlignMatchAtEndOfText(t *testing.T) {
+	// The last line matches in the middle, up to the end of the text
+	srt := "1\n00:00:01,000 --> 00:00:02,000\nGood morning\n\n2\n00:00:02,500 --> 00:00:03,000\nHola amigo\n"
+	subt := newTestSubtitleWithOptions(t, srt, "Buenos días Hola amigo", AlignOptions{MiniLinesMidText: true})
+	if lines := subt.GetTranslatedLines(); subt.CountLineSets() != 2 || lines[0] != "Buenos días" || lines[1] != "Hola amigo" {
+		t.Fatalf("MiniLinesMidText: unexpected lines %q", lines)
+	}
+}
+
+func TestExactMatchLastLineSet(t *testing.T) {
+	// The last line is matched exactly in the middle of the text
+	srt := "1\n00:00:01,000 --> 00:00:02,000\nGood morning\n\n2\n00:00:02,500 --> 00:00:03,000\nThank you very much\n"
+	cases := []struct {
+		txt   string
+		sets  []string
+		exact bool
+	}{
+		// Unchanged: nothing matched, a single LineSet
+		{"Buenos días y adiós", []string{"Buenos días y adiós"}, false},
+		// Changed: the exact LineSet at the end of the text was dropped
+		{"Buenos días Thank you very much", []string{"Buenos días", "Thank you very much"}, true},
+		// Changed: the text left after the match replaced the matched one
+		{"Buenos días Thank you very much señor", []string{"Buenos días", "Thank you very much señor"}, false},
+	}
+	for _, c := range cases {
+		subt := newTestSubtitle(srt, c.txt, ExactMatchAlignment)
+		if strings.Join(subt.translatedSet, "|") != strings.Join(c.sets, "|") {
+			t.Fatalf("SetTranslatedText(%q): want %q have %q", c.txt, c.sets, subt.translatedSet)
+		}
+		last := subt.lineSet[len(subt.lineSet)-1]
+		if last.LastLine != 1 || last.Exact != c.exact || !subt.IsTranslationConsistent() {
+			t.Fatalf("SetTranslatedText(%q): unexpected last LineSet %+v", c.txt, last)
+		}
+	}
+}
+
+func TestAlignOptionsIgnoreCase(t *testing.T) {
+	srt := alignOptionsTestSrt("Hola amigo")
+	txt := "Buenos días HOLA AMIGO Adiós"
+
+	subt := newTestSubtitleWithOptions(t, srt, txt, AlignOptions{MinMatch: 5})
+	if subt.CountLineSets() != 1 {
+		t.Fatalf("IgnoreCase false: want 1 LineSet, have %d", subt.CountLineSets())
+	}
+	subt = newTestSubtitleWithOptions(t, srt, txt, AlignOptions{MinMatch: 5, IgnoreCase: true})
+	if subt.CountLineSets() != 3 {
+		t.Fatalf("IgnoreCase true: want 3 LineSets, have %d", subt.CountLineSets())
+	}
+}
+
+func TestAlignOptionsBracketPattern(t *testing.T) {
+	srt := alignOptionsTestSrt("(Music)")
+	txt := "Buenos días (Música) Adiós"
+
+	subt := newTestSubtitleWithOptions(t, srt, txt, AlignOptions{})
+	if subt.CountLineSets() != 1 {
+		t.Fatalf("BracketPattern default: want 1 LineSet, have %d", subt.CountLineSets())
+	}
+	subt = newTestSubtitleWithOptions(t, srt, txt, AlignOptions{BracketPattern: `\(([^)]+)\)`})
+	if subt.CountLineSets() != 3 {
+		t.Fatalf("BracketPattern: want 3 LineSets, have %d", subt.CountLineSets())
+	}
+	if subt.GetTranslatedLines()[1] != "(Música)" {
+		t.Fatalf("BracketPattern: Line 1: want '(Música)' have '%s'", subt.GetTranslatedLines()[1])
+	}
+}
+
+func TestBracketLineRule(t *testing.T) {
+	// Both splitters take a line containing the pattern as a bracket line
+	subt := SubtitleSRT{originalLine: []string{"Good morning,", "(Music) plays", "Goodbye."},
+		alignOptions: AlignOptions{BracketPattern: `\(([^)]+)\)`}}
+	units := subt.originalAlignUnits()
+	if len(units) != 3 || units[1].initLine != 1 || units[1].lastLine != 1 {
+		t.Fatalf("originalAlignUnits(): want line 1 alone, have %+v", units)
+	}
+	bracketLine, _ := subt.alignOptions.bracketRegexps()
+	if !bracketLine.MatchString(subt.originalLine[1]) {
+		t.Fatal("bracketRegexps(): want a match for line 1")
+	}
+}
+
+func TestAlignOptionsEmptyLineToken(t *testing.T) {
+	srt := "1\n00:00:01,000 --> 00:00:02,000\nGood morning\n\n" +
+		"2\n00:00:02,500 --> 00:00:03,000\n\n\n" +
+		"3\n00:00:03,500 --> 00:00:04,000\nGoodbye\n"
+
+	subt := newTestSubtitleWithOptions(t, srt, "Buenos días <> Adiós", AlignOptions{EmptyLineToken: "<>"})
+	if subt.CountLineSets() != 3 {
+		t.Fatalf("EmptyLineToken: want 3 LineSets, have %d", subt.CountLineSets())
+	}
+	want := []string{"Buenos días", "", "Adiós"}
+	for i, str := range want {
+		if subt.GetTranslatedLines()[i] != str {
+			t.Fatalf("EmptyLineToken: Line %d: want '%s' have '%s'", i, str, subt.GetTranslatedLines()[i])
+		}
+	}
+}
+
+func TestAlignOptionsValidate(t *testing.T) {
+	var subt SubtitleSRT
+	subt.SetOriginalSrt(strings.NewReader(alignOptionsTestSrt("Hola")))
+	if err := subt.SetTranslatedTextWithOptions("Hola", AlignOptions{BracketPattern: `(`}); err == nil {
+		t.Fatal("SetTranslatedTextWithOptions: want error for an invalid BracketPattern")
+	}
+	if err := subt.SetTranslatedTextWithOptions("Hola", AlignOptions{EmptyLineToken: "[ ]"}); err == nil {
+		t.Fatal("SetTranslatedTextWithOptions: want error for an EmptyLineToken with spaces")
+	}
+}
+
+func TestLineSetConfidence(t *testing.T) {
+	// The translation of the fifth sentence is far too short
+	txt := strings.Replace(alignTestTxt, "hace dos mil años por un pequeño grupo de pescadores.", "hace años.", 1)
+	subt := newTestSubtitle(alignTestSrt, txt, StatisticalAlignment)
+
+	queue := subt.GetLineSetsByConfidence()
+	if len(queue) != subt.CountLineSets() {
+		t.Fatalf("GetLineSetsByConfidence(): want %d LineSets have %d", subt.CountLineSets(), len(queue))
+	}
+	if queue[0].LineSet != 4 || queue[0].RatioDeviation >= 0 {
+		t.Fatalf("GetLineSetsByConfidence(): want LineSet 4 first with negative deviation, have %+v", queue[0])
+	}
+	for i := 1; i < len(queue); i++ {
+		if queue[i].Score < queue[i-1].Score {
+			t.Fatalf("GetLineSetsByConfidence(): not sorted at %d: %+v", i, queue)
+		}
+	}
+}
+
+func TestLineSetConfidenceExact(t *testing.T) {
+	subt := newTestSubtitleWithOptions(t, alignOptionsTestSrt("Hola amigo"), "Buenos días Hola amigo Adiós", AlignOptions{MinMatch: 5})
+
+	exact := subt.CalculateConfidenceOfLineSet(1)
+	inferred := subt.CalculateConfidenceOfLineSet(0)
+	if !exact.Exact || inferred.Exact {
+		t.Fatalf("CalculateConfidenceOfLineSet(): want LineSet 1 exact and 0 inferred, have %+v %+v", exact, inferred)
+	}
+	if exact.Score <= inferred.Score {
+		t.Fatalf("CalculateConfidenceOfLineSet(): exact Score %f should be above inferred %f", exact.Score, inferred.Score)
+	}
+}
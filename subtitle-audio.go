@@ -0,0 +1,578 @@
+package subtitle
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"time"
+)
+
+// ------------------------------------------------------
+// Synchronization of the blocks to the speech of a WAV
+// ------------------------------------------------------
+
+// AudioSamples are the samples of an audio, mixed to mono in [-1, 1]
+type AudioSamples struct {
+	SampleRate int
+	Samples    []float64
+}
+
+// Duration returns the length of the audio
+func (a *AudioSamples) Duration() time.Duration {
+	if a.SampleRate == 0 {
+		return 0
+	}
+	return time.Duration(len(a.Samples)) * time.Second / time.Duration(a.SampleRate)
+}
+
+// Formats of the samples of a WAV file
+const (
+	wavFormatPCM        = 1
+	wavFormatFloat      = 3
+	wavFormatExtensible = 0xFFFE
+)
+
+// wavReader reads the samples of the data chunk of a WAV file, as needed
+//   - data is the rest of the data chunk
+//   - buf holds the bytes of the frames being decoded
+type wavReader struct {
+	sampleRate int
+	channels   int
+	width      int
+	decode     func([]byte) float64
+	data       io.Reader
+	buf        []byte
+}
+
+// Max size of the format chunk of a WAV file
+const maxWAVFormatSize = 1024
+
+// newWAVReader reads the header of a WAV file up to the start of its data
+// chunk. The format chunk must be before the data chunk
+func newWAVReader(reader io.Reader) (*wavReader, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(reader, header[:]); err != nil || string(header[0:4]) != "RIFF" || string(header[8:12]) != "WAVE" {
+		return nil, fmt.Errorf("subtitle: not a WAV file")
+	}
+	var format, channels, bits, sampleRate int
+	for {
+		var chunk [8]byte
+		if _, err := io.ReadFull(reader, chunk[:]); err != nil {
+			return nil, fmt.Errorf("subtitle: WAV file without format or data")
+		}
+		id := string(chunk[0:4])
+		size := int64(binary.LittleEndian.Uint32(chunk[4:8]))
+		switch id {
+		case "fmt ":
+			if size < 16 || size > maxWAVFormatSize {
+				return nil, fmt.Errorf("subtitle: invalid WAV format chunk")
+			}
+			// The chunks are word aligned
+			data := make([]byte, size+size%2)
+			if _, err := io.ReadFull(reader, data); err != nil {
+				return nil, fmt.Errorf("subtitle: invalid WAV format chunk")
+			}
+			format = int(binary.LittleEndian.Uint16(data[0:2]))
+			channels = int(binary.LittleEndian.Uint16(data[2:4]))
+			sampleRate = int(binary.LittleEndian.Uint32(data[4:8]))
+			bits = int(binary.LittleEndian.Uint16(data[14:16]))
+			// The format of an extensible WAV is in its sub format
+			if format == wavFormatExtensible && size >= 26 {
+				format = int(binary.LittleEndian.Uint16(data[24:26]))
+			}
+		case "data":
+			if channels == 0 || sampleRate == 0 {
+				return nil, fmt.Errorf("subtitle: WAV file without format or data")
+			}
+			decode, err := wavSampleDecoder(format, bits)
+			if err != nil {
+				return nil, err
+			}
+			return &wavReader{sampleRate, channels, bits / 8, decode, io.LimitReader(reader, size), nil}, nil
+		default:
+			if _, err := io.CopyN(io.Discard, reader, size+size%2); err != nil {
+				return nil, fmt.Errorf("subtitle: WAV file without format or data")
+			}
+		}
+	}
+}
+
+// read decodes the next samples, mixed to mono, and returns how many
+// were read: 0 at the end of the data
+func (this *wavReader) read(samples []float64) (int, error) {
+	frameBytes := this.width * this.channels
+	if len(this.buf) < len(samples)*frameBytes {
+		this.buf = make([]byte, len(samples)*frameBytes)
+	}
+	n, err := io.ReadFull(this.data, this.buf[:len(samples)*frameBytes])
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		err = nil
+	}
+	frames := n / frameBytes
+	for i := 0; i < frames; i++ {
+		sum := 0.0
+		for c := 0; c < this.channels; c++ {
+			pos := (i*this.channels + c) * this.width
+			sum += this.decode(this.buf[pos : pos+this.width])
+		}
+		samples[i] = sum / float64(this.channels)
+	}
+	return frames, err
+}
+
+// ReadWAV reads a WAV file of integer PCM samples (8, 16, 24 or 32 bits)
+// or float samples (32 or 64 bits). The channels are mixed to mono
+func ReadWAV(reader io.Reader) (*AudioSamples, error) {
+	wav, err := newWAVReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	audio := &AudioSamples{SampleRate: wav.sampleRate}
+	samples := make([]float64, wav.sampleRate)
+	for {
+		n, err := wav.read(samples)
+		if err != nil {
+			return nil, err
+		}
+		if n == 0 {
+			return audio, nil
+		}
+		audio.Samples = append(audio.Samples, samples[:n]...)
+	}
+}
+
+// wavSampleDecoder returns the function that decodes a sample to [-1, 1]
+func wavSampleDecoder(format, bits int) (func([]byte) float64, error) {
+	switch {
+	case format == wavFormatPCM && bits == 8:
+		return func(b []byte) float64 { return (float64(b[0]) - 128) / 128 }, nil
+	case format == wavFormatPCM && bits == 16:
+		return func(b []byte) float64 { return float64(int16(binary.LittleEndian.Uint16(b))) / (1 << 15) }, nil
+	case format == wavFormatPCM && bits == 24:
+		return func(b []byte) float64 {
+			return float64(int32(uint32(b[0])<<8|uint32(b[1])<<16|uint32(b[2])<<24)>>8) / (1 << 23)
+		}, nil
+	case format == wavFormatPCM && bits == 32:
+		return func(b []byte) float64 { return float64(int32(binary.LittleEndian.Uint32(b))) / (1 << 31) }, nil
+	case format == wavFormatFloat && bits == 32:
+		return func(b []byte) float64 { return float64(math.Float32frombits(binary.LittleEndian.Uint32(b))) }, nil
+	case format == wavFormatFloat && bits == 64:
+		return func(b []byte) float64 { return math.Float64frombits(binary.LittleEndian.Uint64(b)) }, nil
+	}
+	return nil, fmt.Errorf("subtitle: WAV format %d with %d bits not supported", format, bits)
+}
+
+// VADOptions configures the voice activity detector
+type VADOptions struct {
+	// FrameDuration is the length of the analysed frames (0 means the default, 10ms)
+	FrameDuration time.Duration
+	// ThresholdDB is the energy over the noise floor of a voiced frame
+	// (0 means the default, 12dB)
+	ThresholdDB float64
+	// MaxZeroCrossingRate is the max rate of sign changes of the samples of a
+	// voiced frame, to discard broadband noise (0 means the default, 0.5)
+	MaxZeroCrossingRate float64
+	// MinSpeech and MinSilence are the shortest speech and silence kept
+	// (0 means the defaults, 100ms and 300ms)
+	MinSpeech  time.Duration
+	MinSilence time.Duration
+}
+
+// Default values of VADOptions
+const (
+	defaultVADFrame      = 10 * time.Millisecond
+	defaultVADThreshold  = 12
+	defaultVADMaxZCR     = 0.5
+	defaultVADMinSpeech  = 100 * time.Millisecond
+	defaultVADMinSilence = 300 * time.Millisecond
+	vadNoiseFloorPercent = 10
+)
+
+// frameDuration returns FrameDuration or its default
+func (o VADOptions) frameDuration() time.Duration {
+	if o.FrameDuration <= 0 {
+		return defaultVADFrame
+	}
+	return o.FrameDuration
+}
+
+// thresholdDB returns ThresholdDB or its default
+func (o VADOptions) thresholdDB() float64 {
+	if o.ThresholdDB <= 0 {
+		return defaultVADThreshold
+	}
+	return o.ThresholdDB
+}
+
+// maxZeroCrossingRate returns MaxZeroCrossingRate or its default
+func (o VADOptions) maxZeroCrossingRate() float64 {
+	if o.MaxZeroCrossingRate <= 0 {
+		return defaultVADMaxZCR
+	}
+	return o.MaxZeroCrossingRate
+}
+
+// minSpeech returns MinSpeech or its default
+func (o VADOptions) minSpeech() time.Duration {
+	if o.MinSpeech <= 0 {
+		return defaultVADMinSpeech
+	}
+	return o.MinSpeech
+}
+
+// minSilence returns MinSilence or its default
+func (o VADOptions) minSilence() time.Duration {
+	if o.MinSilence <= 0 {
+		return defaultVADMinSilence
+	}
+	return o.MinSilence
+}
+
+// VoiceActivity tells whether each frame of an audio has speech
+type VoiceActivity struct {
+	FrameDuration time.Duration
+	Voiced        []bool
+}
+
+// SpeechRegion is a time interval with speech
+type SpeechRegion struct {
+	Start time.Duration
+	End   time.Duration
+}
+
+// vadFrames computes the energy and the zero crossing rate of the frames
+// of an audio, as its samples are added
+//   - size and duration are the ones of a frame
+//   - sum, crossings, count and last are the ones of the frame being added
+type vadFrames struct {
+	size      int
+	duration  time.Duration
+	energy    []float64
+	zcr       []float64
+	sum       float64
+	crossings int
+	count     int
+	last      float64
+}
+
+// newVADFrames returns the vadFrames of an audio with a sample rate
+func newVADFrames(sampleRate int, opts VADOptions) *vadFrames {
+	duration := opts.frameDuration()
+	size := int(int64(sampleRate) * int64(duration) / int64(time.Second))
+	if size < 2 {
+		size = 2
+	}
+	// The frames have a whole number of samples, the duration is theirs
+	if sampleRate > 0 {
+		duration = time.Duration(size) * time.Second / time.Duration(sampleRate)
+	}
+	return &vadFrames{size: size, duration: duration}
+}
+
+// add adds the next samples, a partial frame at the end is not analysed
+func (this *vadFrames) add(samples []float64) {
+	for _, s := range samples {
+		this.sum += s * s
+		if this.count > 0 && (s >= 0) != (this.last >= 0) {
+			this.crossings++
+		}
+		this.last = s
+		this.count++
+		if this.count == this.size {
+			this.energy = append(this.energy, 10*math.Log10(this.sum/float64(this.size)+1e-12))
+			this.zcr = append(this.zcr, float64(this.crossings)/float64(this.size-1))
+			this.sum, this.crossings, this.count = 0, 0, 0
+		}
+	}
+}
+
+// activity returns the frames with speech: the ones with energy over
+// the noise floor and a low zero crossing rate
+func (this *vadFrames) activity(opts VADOptions) VoiceActivity {
+	// The noise floor is the energy of the quietest frames
+	n := len(this.energy)
+	activity := VoiceActivity{this.duration, make([]bool, n)}
+	if n == 0 {
+		return activity
+	}
+	sorted := append([]float64(nil), this.energy...)
+	sort.Float64s(sorted)
+	threshold := sorted[n*vadNoiseFloorPercent/100] + opts.thresholdDB()
+	for f := range activity.Voiced {
+		activity.Voiced[f] = this.energy[f] > threshold && this.zcr[f] <= opts.maxZeroCrossingRate()
+	}
+
+	// Fill the short silences, then remove the short speeches
+	fillRuns(activity.Voiced, false, int(opts.minSilence()/this.duration))
+	fillRuns(activity.Voiced, true, int(opts.minSpeech()/this.duration))
+	return activity
+}
+
+// DetectVoiceActivity finds the frames with speech of an audio: the frames
+// with energy over the noise floor and a low zero crossing rate
+func DetectVoiceActivity(audio *AudioSamples, opts VADOptions) VoiceActivity {
+	frames := newVADFrames(audio.SampleRate, opts)
+	frames.add(audio.Samples)
+	return frames.activity(opts)
+}
+
+// DetectWAVVoiceActivity finds the frames with speech of a WAV file, as
+// DetectVoiceActivity, reading its samples as they are analysed
+func DetectWAVVoiceActivity(reader io.Reader, opts VADOptions) (VoiceActivity, error) {
+	wav, err := newWAVReader(reader)
+	if err != nil {
+		return VoiceActivity{}, err
+	}
+	frames := newVADFrames(wav.sampleRate, opts)
+	samples := make([]float64, 64*frames.size)
+	for {
+		n, err := wav.read(samples)
+		if err != nil {
+			return VoiceActivity{}, err
+		}
+		if n == 0 {
+			return frames.activity(opts), nil
+		}
+		frames.add(samples[:n])
+	}
+}
+
+// fillRuns inverts the runs of value shorter than min frames,
+// the runs of false at the start and the end are kept
+func fillRuns(frames []bool, value bool, min int) {
+	for i := 0; i < len(frames); {
+		if frames[i] != value {
+			i++
+			continue
+		}
+		j := i
+		for j < len(frames) && frames[j] == value {
+			j++
+		}
+		inner := value || (i > 0 && j < len(frames))
+		if j-i < min && inner {
+			for k := i; k < j; k++ {
+				frames[k] = !value
+			}
+		}
+		i = j
+	}
+}
+
+// Regions returns the intervals with speech
+func (v VoiceActivity) Regions() []SpeechRegion {
+	var regions []SpeechRegion
+	for i := 0; i < len(v.Voiced); {
+		if !v.Voiced[i] {
+			i++
+			continue
+		}
+		j := i
+		for j < len(v.Voiced) && v.Voiced[j] {
+			j++
+		}
+		regions = append(regions, SpeechRegion{time.Duration(i) * v.FrameDuration, time.Duration(j) * v.FrameDuration})
+		i = j
+	}
+	return regions
+}
+
+// AudioSyncOptions configures the search of the synchronization
+type AudioSyncOptions struct {
+	// VAD configures the voice activity detector
+	VAD VADOptions
+	// MaxOffset is the max offset searched (0 means the default, 60s)
+	MaxOffset time.Duration
+	// Drift searches a linear drift as well as the offset
+	Drift bool
+	// MaxDrift is the max drift accepted, as a fraction (0 means the default, 0.01)
+	MaxDrift float64
+}
+
+// Default values of AudioSyncOptions
+const (
+	defaultMaxAudioOffset = 60 * time.Second
+	defaultMaxAudioDrift  = 0.01
+	audioDriftGroups      = 8
+	audioDriftWindow      = 2 * time.Second
+)
+
+// maxOffset returns MaxOffset or its default
+func (o AudioSyncOptions) maxOffset() time.Duration {
+	if o.MaxOffset <= 0 {
+		return defaultMaxAudioOffset
+	}
+	return o.MaxOffset
+}
+
+// maxDrift returns MaxDrift or its default
+func (o AudioSyncOptions) maxDrift() float64 {
+	if o.MaxDrift <= 0 {
+		return defaultMaxAudioDrift
+	}
+	return o.MaxDrift
+}
+
+// AudioSync is the retiming of the blocks to the speech:
+// the new time of t is t*Drift + Offset
+//   - Drift is 1 when no drift is searched or found
+//   - Score is the fraction of the time of the blocks with speech
+type AudioSync struct {
+	Offset time.Duration
+	Drift  float64
+	Score  float64
+}
+
+// blockFrames returns the first and last (not included) frames of the
+// blocks with a valid time mark
+func (this *SubtitleSRT) blockFrames(frame time.Duration) ([]int, []int) {
+	var starts, ends []int
+	for _, sbt := range this.subtitleBlock {
+		start, end, err := sbt.Times()
+		if err != nil || end <= start {
+			continue
+		}
+		starts = append(starts, int(start/frame))
+		ends = append(ends, int(end/frame))
+	}
+	return starts, ends
+}
+
+// speechScore returns the score of each offset from the frame from to to:
+// the frames of the blocks with speech minus the frames without it
+func speechScore(prefix []int, starts, ends []int, from, to int) []int {
+	at := func(f int) int {
+		if f < 0 {
+			return 0
+		}
+		if f >= len(prefix) {
+			return prefix[len(prefix)-1]
+		}
+		return prefix[f]
+	}
+	scores := make([]int, to-from+1)
+	for k := from; k <= to; k++ {
+		for b := range starts {
+			scores[k-from] += at(ends[b]+k) - at(starts[b]+k)
+		}
+	}
+	return scores
+}
+
+// bestOffset returns the offset with the best score, the smallest if several
+func bestOffset(scores []int, from int) (int, int) {
+	abs := func(k int) int {
+		if k < 0 {
+			return -k
+		}
+		return k
+	}
+	best := 0
+	for k := range scores {
+		if scores[k] > scores[best] || (scores[k] == scores[best] && abs(k+from) < abs(best+from)) {
+			best = k
+		}
+	}
+	return best + from, scores[best]
+}
+
+// FindAudioSync finds the offset, and the drift if requested, that best
+// aligns the blocks to the speech of the audio
+func (this *SubtitleSRT) FindAudioSync(voice VoiceActivity, opts AudioSyncOptions) (AudioSync, error) {
+	frame := voice.FrameDuration
+	starts, ends := this.blockFrames(frame)
+	if len(starts) == 0 || len(voice.Voiced) == 0 {
+		return AudioSync{}, fmt.Errorf("subtitle: no blocks or no audio to synchronize")
+	}
+	// prefix[f] is the speech minus the silence of the frames before f
+	prefix := make([]int, len(voice.Voiced)+1)
+	for f, voiced := range voice.Voiced {
+		prefix[f+1] = prefix[f] - 1
+		if voiced {
+			prefix[f+1] = prefix[f] + 1
+		}
+	}
+	max := int(opts.maxOffset() / frame)
+	offset, _ := bestOffset(speechScore(prefix, starts, ends, -max, max), -max)
+	sync := AudioSync{Offset: time.Duration(offset) * frame, Drift: 1}
+
+	// The drift is the slope of the offsets of groups of blocks
+	if opts.Drift && len(starts) >= 2*audioDriftGroups {
+		window := int(audioDriftWindow / frame)
+		var xs, ys []float64
+		for g := 0; g < audioDriftGroups; g++ {
+			first, last := g*len(starts)/audioDriftGroups, (g+1)*len(starts)/audioDriftGroups
+			local, _ := bestOffset(speechScore(prefix, starts[first:last], ends[first:last], offset-window, offset+window), offset-window)
+			center := float64(starts[first]+ends[last-1]) / 2
+			xs = append(xs, center)
+			ys = append(ys, float64(local))
+		}
+		slope, intercept := fitLine(xs, ys)
+		if math.Abs(slope) <= opts.maxDrift() {
+			sync.Drift = 1 + slope
+			sync.Offset = time.Duration(intercept * float64(frame))
+		}
+	}
+
+	// The score of the retimed blocks
+	total, voiced := 0, 0
+	for b := range starts {
+		s := int(float64(starts[b])*sync.Drift + float64(sync.Offset/frame))
+		e := int(float64(ends[b])*sync.Drift + float64(sync.Offset/frame))
+		for f := s; f < e; f++ {
+			total++
+			if f >= 0 && f < len(voice.Voiced) && voice.Voiced[f] {
+				voiced++
+			}
+		}
+	}
+	if total > 0 {
+		sync.Score = float64(voiced) / float64(total)
+	}
+	return sync, nil
+}
+
+// fitLine returns the slope and intercept of the least squares line
+func fitLine(xs, ys []float64) (float64, float64) {
+	n := float64(len(xs))
+	var sx, sy, sxx, sxy float64
+	for i := range xs {
+		sx += xs[i]
+		sy += ys[i]
+		sxx += xs[i] * xs[i]
+		sxy += xs[i] * ys[i]
+	}
+	den := n*sxx - sx*sx
+	if den == 0 {
+		return 0, sy / n
+	}
+	slope := (n*sxy - sx*sy) / den
+	return slope, (sy - slope*sx) / n
+}
+
+// ApplyAudioSync retimes all the blocks, if any, with an AudioSync
+func (this *SubtitleSRT) ApplyAudioSync(sync AudioSync) (err error) {
+	defer this.beginEdit("ApplyAudioSync", sync)(&err)
+	if len(this.subtitleBlock) == 0 {
+		return nil
+	}
+	return this.retimeBlocks(0, len(this.subtitleBlock)-1, func(t time.Duration) time.Duration {
+		return time.Duration(float64(t)*sync.Drift) + sync.Offset
+	})
+}
+
+// SyncToAudio reads a WAV file, finds the synchronization of the blocks
+// to its speech and applies it. The samples are not kept in memory
+func (this *SubtitleSRT) SyncToAudio(reader io.Reader, opts AudioSyncOptions) (AudioSync, error) {
+	voice, err := DetectWAVVoiceActivity(reader, opts.VAD)
+	if err != nil {
+		return AudioSync{}, err
+	}
+	sync, err := this.FindAudioSync(voice, opts)
+	if err != nil {
+		return AudioSync{}, err
+	}
+	return sync, this.ApplyAudioSync(sync)
+}
@@ -0,0 +1,184 @@
+package subtitle
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+)
+
+// writeWAV writes 16 bits mono PCM samples as a WAV file
+func writeWAV(audio *AudioSamples) []byte {
+	var buf bytes.Buffer
+	size := uint32(2 * len(audio.Samples))
+	buf.WriteString("RIFF")
+	binary.Write(&buf, binary.LittleEndian, 36+size)
+	buf.WriteString("WAVEfmt ")
+	binary.Write(&buf, binary.LittleEndian, uint32(16))
+	binary.Write(&buf, binary.LittleEndian, []uint16{wavFormatPCM, 1})
+	binary.Write(&buf, binary.LittleEndian, []uint32{uint32(audio.SampleRate), uint32(2 * audio.SampleRate)})
+	binary.Write(&buf, binary.LittleEndian, []uint16{2, 16})
+	buf.WriteString("data")
+	binary.Write(&buf, binary.LittleEndian, size)
+	samples := make([]int16, len(audio.Samples))
+	for i, s := range audio.Samples {
+		samples[i] = int16(math.Max(-1, math.Min(1, s)) * math.MaxInt16)
+	}
+	binary.Write(&buf, binary.LittleEndian, samples)
+	return buf.Bytes()
+}
+
+// audioTestSrt returns a SRT of 40 blocks with irregular times and durations
+func audioTestSrt() string {
+	var srt strings.Builder
+	for i := 0; i < 40; i++ {
+		start := 2*time.Second + time.Duration(i)*3*time.Second + time.Duration(i%3)*400*time.Millisecond
+		end := start + time.Second + time.Duration(i%4)*300*time.Millisecond
+		fmt.Fprintf(&srt, "%d\n%s\nLine %d.\n\n", i+1, FormatTimemark(start, end), i+1)
+	}
+	return srt.String()
+}
+
+// audioTestWAV returns a WAV with a tone where each block of the SRT
+// would be after retiming it with drift and offset, and noise elsewhere
+func audioTestWAV(srt string, drift float64, offset time.Duration) []byte {
+	var subt SubtitleSRT
+	subt.SetOriginalSrt(strings.NewReader(srt))
+	audio := &AudioSamples{8000, make([]float64, 8000*130)}
+	seed := uint32(1)
+	for i := range audio.Samples {
+		seed = seed*1664525 + 1013904223
+		audio.Samples[i] = (float64(seed>>16)/65536 - 0.5) * 0.002
+	}
+	for _, sbt := range subt.GetSubtitleBlocks() {
+		start, end, _ := sbt.Times()
+		from := int((time.Duration(float64(start)*drift) + offset).Seconds() * 8000)
+		to := int((time.Duration(float64(end)*drift) + offset).Seconds() * 8000)
+		for i := from; i < to && i < len(audio.Samples); i++ {
+			audio.Samples[i] = 0.5 * math.Sin(2*math.Pi*220*float64(i)/8000)
+		}
+	}
+	return writeWAV(audio)
+}
+
+func TestReadWAV(t *testing.T) {
+	audio, err := ReadWAV(bytes.NewReader(writeWAV(&AudioSamples{8000, []float64{0, 0.5, -0.5, 1}})))
+	if err != nil {
+		t.Fatalf("ReadWAV(): %v", err)
+	}
+	if audio.SampleRate != 8000 || len(audio.Samples) != 4 || math.Abs(audio.Samples[1]-0.5) > 1e-4 {
+		t.Fatalf("ReadWAV(): unexpected %+v", audio)
+	}
+	if _, err := ReadWAV(strings.NewReader("RIFF....WAVE")); err == nil {
+		t.Fatal("ReadWAV(): want error without data")
+	}
+}
+
+func TestReadWAVChunks(t *testing.T) {
+	// A LIST chunk of odd size before the data, read one byte at a time
+	wav := writeWAV(&AudioSamples{8000, []float64{0, 0.5, -0.5, 1}})
+	list := append([]byte("LIST\x03\x00\x00\x00abc"), 0)
+	data := bytes.Index(wav, []byte("data"))
+	wav = append(append(append([]byte(nil), wav[:data]...), list...), wav[data:]...)
+	audio, err := ReadWAV(iotest.OneByteReader(bytes.NewReader(wav)))
+	if err != nil {
+		t.Fatalf("ReadWAV(): %v", err)
+	}
+	if audio.SampleRate != 8000 || len(audio.Samples) != 4 || math.Abs(audio.Samples[3]-1) > 1e-4 {
+		t.Fatalf("ReadWAV(): unexpected %+v", audio)
+	}
+	// A truncated data chunk has the samples read
+	if audio, err := ReadWAV(bytes.NewReader(wav[:len(wav)-3])); err != nil || len(audio.Samples) != 2 {
+		t.Fatalf("ReadWAV(): unexpected %+v %v", audio, err)
+	}
+}
+
+func TestDetectWAVVoiceActivity(t *testing.T) {
+	wav := audioTestWAV(audioTestSrt(), 1, 0)
+	audio, err := ReadWAV(bytes.NewReader(wav))
+	if err != nil {
+		t.Fatalf("ReadWAV(): %v", err)
+	}
+	want := DetectVoiceActivity(audio, VADOptions{})
+	have, err := DetectWAVVoiceActivity(bytes.NewReader(wav), VADOptions{})
+	if err != nil {
+		t.Fatalf("DetectWAVVoiceActivity(): %v", err)
+	}
+	if have.FrameDuration != want.FrameDuration || fmt.Sprint(have.Regions()) != fmt.Sprint(want.Regions()) {
+		t.Fatalf("DetectWAVVoiceActivity(): want %v have %v", want.Regions(), have.Regions())
+	}
+	if _, err := DetectWAVVoiceActivity(strings.NewReader("RIFF....WAVE"), VADOptions{}); err == nil {
+		t.Fatal("DetectWAVVoiceActivity(): want error without data")
+	}
+}
+
+func TestDetectVoiceActivitySampleRates(t *testing.T) {
+	// 10ms are 220.5 samples at 22050 Hz and 110.25 at 11025 Hz
+	for _, rate := range []int{22050, 11025} {
+		audio := &AudioSamples{rate, make([]float64, rate*60)}
+		seed := uint32(1)
+		for i := range audio.Samples {
+			seed = seed*1664525 + 1013904223
+			audio.Samples[i] = (float64(seed>>16)/65536 - 0.5) * 0.002
+		}
+		for i := rate * 50; i < rate*55; i++ {
+			audio.Samples[i] = 0.5 * math.Sin(2*math.Pi*220*float64(i)/float64(rate))
+		}
+
+		voice := DetectVoiceActivity(audio, VADOptions{})
+		frame := time.Duration(rate/100) * time.Second / time.Duration(rate)
+		if voice.FrameDuration != frame {
+			t.Fatalf("DetectVoiceActivity(%d Hz): want frames of %v have %v", rate, frame, voice.FrameDuration)
+		}
+		regions := voice.Regions()
+		if len(regions) != 1 {
+			t.Fatalf("DetectVoiceActivity(%d Hz): unexpected regions %+v", rate, regions)
+		}
+		if d := regions[0].Start - 50*time.Second; d < -frame || d > frame {
+			t.Fatalf("DetectVoiceActivity(%d Hz): speech starts at %v", rate, regions[0].Start)
+		}
+		if d := regions[0].End - 55*time.Second; d < -frame || d > frame {
+			t.Fatalf("DetectVoiceActivity(%d Hz): speech ends at %v", rate, regions[0].End)
+		}
+	}
+}
+
+func TestSyncToAudio(t *testing.T) {
+	srt := audioTestSrt()
+	var subt SubtitleSRT
+	subt.SetOriginalSrt(strings.NewReader(srt))
+
+	sync, err := subt.SyncToAudio(bytes.NewReader(audioTestWAV(srt, 1, 1230*time.Millisecond)), AudioSyncOptions{})
+	if err != nil {
+		t.Fatalf("SyncToAudio(): %v", err)
+	}
+	if sync.Offset != 1230*time.Millisecond || sync.Drift != 1 || sync.Score < 0.95 {
+		t.Fatalf("SyncToAudio(): unexpected %+v", sync)
+	}
+	if blocks := subt.GetSubtitleBlocks(); blocks[0].Timemark != "00:00:03,230 --> 00:00:04,230" {
+		t.Fatalf("SyncToAudio(): unexpected %+v", blocks[0])
+	}
+}
+
+func TestSyncToAudioDrift(t *testing.T) {
+	srt := audioTestSrt()
+	var subt SubtitleSRT
+	subt.SetOriginalSrt(strings.NewReader(srt))
+
+	sync, err := subt.SyncToAudio(bytes.NewReader(audioTestWAV(srt, 1.004, 800*time.Millisecond)), AudioSyncOptions{Drift: true})
+	if err != nil {
+		t.Fatalf("SyncToAudio(): %v", err)
+	}
+	if math.Abs(sync.Drift-1.004) > 0.0005 || sync.Score < 0.95 {
+		t.Fatalf("SyncToAudio(): unexpected %+v", sync)
+	}
+	// The last block starts at 119s, at 119*1.004+0.8 = 120.276s in the audio
+	start, _, _ := subt.GetSubtitleBlocks()[39].Times()
+	if d := start - 120276*time.Millisecond; d < -30*time.Millisecond || d > 30*time.Millisecond {
+		t.Fatalf("SyncToAudio(): last block starts at %v", start)
+	}
+}
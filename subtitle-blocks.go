@@ -0,0 +1,233 @@
+package subtitle
+
+import (
+	"fmt"
+	"time"
+)
+
+// ------------------------------------------------------
+// Functions to insert, delete, split and merge blocks
+// ------------------------------------------------------
+
+// The block operations keep the lines, the LineSet:s and the translated
+// text consistent, and renumber the blocks from 1
+// They return an error instead of changing the lines of a locked LineSet
+
+// InsertBlock inserts a block before the block at (at the end if at is the
+// number of blocks), with its original lines and no translation
+// The lines are a LineSet of their own, the LineSet at the point of
+// insertion is split in two if needed
+func (this *SubtitleSRT) InsertBlock(at int, timemark string, lines ...string) (err error) {
+	defer this.beginEdit("InsertBlock", at, timemark, lines)(&err)
+	if at < 0 || at > len(this.subtitleBlock) {
+		return fmt.Errorf("subtitle: invalid block %d", at)
+	}
+	if _, _, err := ParseTimemark(timemark); err != nil {
+		return err
+	}
+	// As in an SRT file, there are no empty lines but an only empty one
+	var original []string
+	for _, l := range lines {
+		if l = this.normalizeOriginal(l); l != "" {
+			original = append(original, l)
+		}
+	}
+	if len(original) == 0 {
+		original = []string{""}
+	}
+
+	line := len(this.originalLine)
+	if at < len(this.subtitleBlock) {
+		line, _ = this.blockLines(at)
+	}
+	// The lines cannot be inserted in the middle of a locked LineSet
+	if ls := this.WhatLineSetIsLine(line); this.IsLineSetLocked(ls) && this.lineSet[ls].InitLine < line {
+		return fmt.Errorf("subtitle: line set %d is locked", ls)
+	}
+	this.insertLines(line, original, make([]string, len(original)))
+
+	this.subtitleBlock = append(this.subtitleBlock, SubtitleBlock{})
+	copy(this.subtitleBlock[at+1:], this.subtitleBlock[at:])
+	this.subtitleBlock[at] = SubtitleBlock{"", timemark, len(original)}
+	this.renumberBlocks()
+	return nil
+}
+
+// DeleteBlock deletes a block and its lines
+// The LineSet:s partially in the block keep the translation of their other lines
+func (this *SubtitleSRT) DeleteBlock(b int) (err error) {
+	defer this.beginEdit("DeleteBlock", b)(&err)
+	if b < 0 || b >= len(this.subtitleBlock) {
+		return fmt.Errorf("subtitle: invalid block %d", b)
+	}
+	init, last := this.blockLines(b)
+	if err := this.checkUnlocked(init, last); err != nil {
+		return err
+	}
+	this.removeLines(init, last)
+	this.subtitleBlock = append(this.subtitleBlock[:b], this.subtitleBlock[b+1:]...)
+	this.renumberBlocks()
+	return nil
+}
+
+// SplitBlock splits a block in two before its line n (1 .. Nlines-1)
+// The time is split in proportion to the chars of the original lines
+func (this *SubtitleSRT) SplitBlock(b, n int) (err error) {
+	defer this.beginEdit("SplitBlock", b, n)(&err)
+	if b < 0 || b >= len(this.subtitleBlock) {
+		return fmt.Errorf("subtitle: invalid block %d", b)
+	}
+	sbt := this.subtitleBlock[b]
+	if n <= 0 || n >= sbt.Nlines {
+		return fmt.Errorf("subtitle: invalid line %d to split block %d", n, b)
+	}
+	start, end, err := sbt.Times()
+	if err != nil {
+		return err
+	}
+	init, last := this.blockLines(b)
+	if err := this.checkUnlocked(init, last); err != nil {
+		return err
+	}
+	first := countChars(joinStrings(this.originalLine[init : init+n]...))
+	all := countChars(joinStrings(this.originalLine[init : last+1]...))
+	split := start + (end-start)/2
+	if all > 0 {
+		split = start + time.Duration(float64(end-start)*float64(first)/float64(all))
+	}
+
+	this.subtitleBlock = append(this.subtitleBlock, SubtitleBlock{})
+	copy(this.subtitleBlock[b+1:], this.subtitleBlock[b:])
+	this.subtitleBlock[b].Nlines = n
+	this.subtitleBlock[b].SetTimes(start, split)
+	this.subtitleBlock[b+1].Nlines = sbt.Nlines - n
+	this.subtitleBlock[b+1].SetTimes(split, end)
+	this.renumberBlocks()
+	return nil
+}
+
+// MergeBlocks merges a block with the next one, from the start of the first
+// to the end of the second. The empty lines of the merged block are deleted
+func (this *SubtitleSRT) MergeBlocks(b int) (err error) {
+	defer this.beginEdit("MergeBlocks", b)(&err)
+	if b < 0 || b >= len(this.subtitleBlock)-1 {
+		return fmt.Errorf("subtitle: invalid block %d to merge with the next one", b)
+	}
+	start, _, err := this.subtitleBlock[b].Times()
+	if err != nil {
+		return err
+	}
+	_, end, err := this.subtitleBlock[b+1].Times()
+	if err != nil {
+		return err
+	}
+	init, _ := this.blockLines(b)
+	_, last := this.blockLines(b + 1)
+	if err := this.checkUnlocked(init, last); err != nil {
+		return err
+	}
+	this.subtitleBlock[b].Nlines += this.subtitleBlock[b+1].Nlines
+	this.subtitleBlock[b].SetTimes(start, end)
+	this.subtitleBlock = append(this.subtitleBlock[:b+1], this.subtitleBlock[b+2:]...)
+
+	// Delete the empty lines, from the last one
+	init, last = this.blockLines(b)
+	for i := last; i >= init && this.subtitleBlock[b].Nlines > 1; i-- {
+		if this.originalLine[i] == "" && this.translatedLine[i] == "" {
+			this.removeLines(i, i)
+			this.subtitleBlock[b].Nlines--
+		}
+	}
+	this.renumberBlocks()
+	return nil
+}
+
+// checkUnlocked returns an error if a locked LineSet has lines from init to last
+func (this *SubtitleSRT) checkUnlocked(init, last int) error {
+	for ls, set := range this.lineSet {
+		if set.Locked && set.InitLine <= last && set.LastLine >= init {
+			return fmt.Errorf("subtitle: line set %d is locked", ls)
+		}
+	}
+	return nil
+}
+
+// insertLines inserts lines before the line at, as a new LineSet
+// with the translation of the translated lines
+func (this *SubtitleSRT) insertLines(at int, original, translated []string) {
+	// The line at must be the first of a LineSet
+	ls := len(this.lineSet)
+	if this.IsSplit() && at < len(this.originalLine) {
+		ls = this.WhatLineSetIsLine(at)
+		if this.lineSet[ls].InitLine < at {
+			this.SplitLineSetByLine(ls, at)
+			ls++
+		}
+	}
+
+	n := len(original)
+	this.originalLine = append(this.originalLine, make([]string, n)...)
+	copy(this.originalLine[at+n:], this.originalLine[at:])
+	copy(this.originalLine[at:], original)
+	this.translatedLine = append(this.translatedLine, make([]string, n)...)
+	copy(this.translatedLine[at+n:], this.translatedLine[at:])
+	copy(this.translatedLine[at:], translated)
+	if !this.IsSplit() {
+		return
+	}
+
+	for i := ls; i < len(this.lineSet); i++ {
+		this.lineSet[i].InitLine += n
+		this.lineSet[i].LastLine += n
+	}
+	this.lineSet = append(this.lineSet, LineSet{})
+	copy(this.lineSet[ls+1:], this.lineSet[ls:])
+	this.lineSet[ls] = LineSet{InitLine: at, LastLine: at + n - 1}
+	this.translatedSet = append(this.translatedSet, "")
+	copy(this.translatedSet[ls+1:], this.translatedSet[ls:])
+	this.translatedSet[ls] = joinStrings(translated...)
+	this.translatedText = joinStrings(this.translatedSet...)
+}
+
+// removeLines removes the lines init to last, both included
+// The LineSet:s in them are deleted, and the ones partially in them
+// keep the translation of their other lines
+func (this *SubtitleSRT) removeLines(init, last int) {
+	n := last - init + 1
+	if this.IsSplit() {
+		var lineSet []LineSet
+		var translatedSet []string
+		for ls, set := range this.lineSet {
+			switch {
+			case set.LastLine < init:
+			case set.InitLine > last:
+				set.InitLine -= n
+				set.LastLine -= n
+			case set.InitLine >= init && set.LastLine <= last:
+				continue
+			default:
+				var lines []string
+				if set.InitLine < init {
+					lines = append(lines, this.translatedLine[set.InitLine:init]...)
+				}
+				if set.LastLine > last {
+					lines = append(lines, this.translatedLine[last+1:set.LastLine+1]...)
+				}
+				if set.InitLine > init {
+					set.InitLine = init
+				}
+				set.LastLine = set.InitLine + len(lines) - 1
+				set.Exact = false
+				lineSet = append(lineSet, set)
+				translatedSet = append(translatedSet, joinStrings(lines...))
+				continue
+			}
+			lineSet = append(lineSet, set)
+			translatedSet = append(translatedSet, this.translatedSet[ls])
+		}
+		this.lineSet, this.translatedSet = lineSet, translatedSet
+		this.translatedText = joinStrings(this.translatedSet...)
+	}
+	this.originalLine = append(this.originalLine[:init], this.originalLine[last+1:]...)
+	this.translatedLine = append(this.translatedLine[:init], this.translatedLine[last+1:]...)
+}
@@ -0,0 +1,144 @@
+package subtitle
+
+import (
+	"testing"
+)
+
+// checkBlocksConsistent fails if the blocks, lines and LineSet:s do not match
+func checkBlocksConsistent(t *testing.T, op string, subt *SubtitleSRT) {
+	t.Helper()
+	lines := 0
+	for b, sbt := range subt.GetSubtitleBlocks() {
+		if sbt.Nlines < 1 || sbt.Order != string(rune('1'+b)) {
+			t.Fatalf("%s: unexpected block %d %+v", op, b, sbt)
+		}
+		lines += sbt.Nlines
+	}
+	if lines != subt.CountLines() || len(subt.GetTranslatedLines()) != lines {
+		t.Fatalf("%s: %d lines in the blocks, %d lines", op, lines, subt.CountLines())
+	}
+	init := 0
+	for _, ls := range subt.GetLineSets() {
+		if ls.InitLine != init || ls.LastLine < ls.InitLine {
+			t.Fatalf("%s: unexpected line sets %+v", op, subt.GetLineSets())
+		}
+		init = ls.LastLine + 1
+	}
+	if init != lines || !subt.IsTranslationConsistent() {
+		t.Fatalf("%s: line sets not consistent %+v", op, subt.GetLineSets())
+	}
+}
+
+func TestInsertDeleteBlock(t *testing.T) {
+	// All the text before [Music] is the LineSet 0..3
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, ExactMatchAlignment)
+
+	if err := subt.InsertBlock(1, "00:00:03,100 --> 00:00:03,400", "Hello", "again."); err != nil {
+		t.Fatalf("InsertBlock(): %v", err)
+	}
+	checkBlocksConsistent(t, "InsertBlock()", &subt)
+	if ls := subt.GetLineSets(); !ls[0].IsEqual(LineSet{InitLine: 0, LastLine: 1}) || !ls[1].IsEqual(LineSet{InitLine: 2, LastLine: 3}) ||
+		!ls[2].IsEqual(LineSet{InitLine: 4, LastLine: 5}) {
+		t.Fatalf("InsertBlock(): unexpected line sets %+v", ls)
+	}
+	if lines := subt.GetOriginalLines(); lines[2] != "Hello" || lines[3] != "again." {
+		t.Fatalf("InsertBlock(): unexpected lines %q", lines)
+	}
+	if err := subt.InsertBlock(7, "00:00:14,000 --> 00:00:15,000"); err != nil {
+		t.Fatalf("InsertBlock(): %v", err)
+	}
+	checkBlocksConsistent(t, "InsertBlock()", &subt)
+
+	if err := subt.DeleteBlock(7); err != nil {
+		t.Fatalf("DeleteBlock(): %v", err)
+	}
+	checkBlocksConsistent(t, "DeleteBlock()", &subt)
+	// Delete the block in the middle of a LineSet
+	if err := subt.DeleteBlock(2); err != nil {
+		t.Fatalf("DeleteBlock(): %v", err)
+	}
+	checkBlocksConsistent(t, "DeleteBlock()", &subt)
+	if subt.CountBlocks() != 6 || subt.CountLines() != 9 {
+		t.Fatalf("DeleteBlock(): %d blocks and %d lines", subt.CountBlocks(), subt.CountLines())
+	}
+
+	if err := subt.InsertBlock(9, "00:00:14,000 --> 00:00:15,000"); err == nil {
+		t.Fatal("InsertBlock(): want error for an invalid block")
+	}
+	if err := subt.InsertBlock(0, "00:00:14,000"); err == nil {
+		t.Fatal("InsertBlock(): want error for an invalid time mark")
+	}
+}
+
+func TestSplitMergeBlocks(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, StatisticalAlignment)
+
+	if err := subt.SplitBlock(0, 1); err != nil {
+		t.Fatalf("SplitBlock(): %v", err)
+	}
+	checkBlocksConsistent(t, "SplitBlock()", &subt)
+	// 24 chars of 56 of 2 seconds
+	blocks := subt.GetSubtitleBlocks()
+	if blocks[0].Timemark != "00:00:01,000 --> 00:00:01,857" || blocks[1].Timemark != "00:00:01,857 --> 00:00:03,000" {
+		t.Fatalf("SplitBlock(): unexpected blocks %+v", blocks)
+	}
+	if err := subt.SplitBlock(3, 1); err == nil {
+		t.Fatal("SplitBlock(): want error for a block of one line")
+	}
+
+	if err := subt.MergeBlocks(0); err != nil {
+		t.Fatalf("MergeBlocks(): %v", err)
+	}
+	checkBlocksConsistent(t, "MergeBlocks()", &subt)
+	if blocks := subt.GetSubtitleBlocks(); blocks[0].Timemark != "00:00:01,000 --> 00:00:03,000" || blocks[0].Nlines != 2 {
+		t.Fatalf("MergeBlocks(): unexpected blocks %+v", blocks)
+	}
+
+	// The empty line of the block 3 is deleted
+	if err := subt.MergeBlocks(2); err != nil {
+		t.Fatalf("MergeBlocks(): %v", err)
+	}
+	checkBlocksConsistent(t, "MergeBlocks()", &subt)
+	if blocks := subt.GetSubtitleBlocks(); subt.CountLines() != 8 || blocks[2].Timemark != "00:00:06,500 --> 00:00:08,000" {
+		t.Fatalf("MergeBlocks(): unexpected blocks %+v", blocks)
+	}
+	if err := subt.MergeBlocks(4); err == nil {
+		t.Fatal("MergeBlocks(): want error for the last block")
+	}
+}
+
+func TestBlocksKeepLockedLineSets(t *testing.T) {
+	// All the text before [Music] is the LineSet 0..3, of blocks 0 and 1
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, ExactMatchAlignment)
+	subt.LockLineSet(0)
+	before := *subt.snapshot()
+
+	if err := subt.InsertBlock(1, "00:00:03,100 --> 00:00:03,400", "Hello."); err == nil {
+		t.Fatal("InsertBlock(): want error in a locked line set")
+	}
+	if err := subt.DeleteBlock(0); err == nil {
+		t.Fatal("DeleteBlock(): want error for a locked line set")
+	}
+	if err := subt.SplitBlock(0, 1); err == nil {
+		t.Fatal("SplitBlock(): want error for a locked line set")
+	}
+	if err := subt.MergeBlocks(1); err == nil {
+		t.Fatal("MergeBlocks(): want error for a locked line set")
+	}
+	if !subt.IsEqual(before) {
+		t.Fatal("Block operations: a locked line set was changed")
+	}
+
+	// Before the locked LineSet and after it, the blocks can change
+	if err := subt.InsertBlock(0, "00:00:00,100 --> 00:00:00,400", "Hello."); err != nil {
+		t.Fatalf("InsertBlock(): %v", err)
+	}
+	checkBlocksConsistent(t, "InsertBlock()", &subt)
+	if err := subt.DeleteBlock(len(subt.GetSubtitleBlocks()) - 1); err != nil {
+		t.Fatalf("DeleteBlock(): %v", err)
+	}
+	checkBlocksConsistent(t, "DeleteBlock()", &subt)
+	if ls := subt.GetLineSets()[1]; !ls.Locked || ls.InitLine != 1 || ls.LastLine != 4 {
+		t.Fatalf("Block operations: unexpected locked line set %+v", ls)
+	}
+}
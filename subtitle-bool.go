@@ -68,7 +68,7 @@ func (this *SubtitleSRT) IsEqual(other SubtitleSRT) bool {
 		return false
 	}
 	for i, l := range this.lineSet {
-		if l != other.lineSet[i] {
+		if !l.IsEqual(other.lineSet[i]) {
 			return false
 		}
 	}
@@ -113,7 +113,13 @@ func (this *SubtitleSRT) IsTranslationConsistent() bool {
 	// Verify the translatedLines
 	theTextLines := joinStrings(this.translatedLine...)
 	theTextSets := joinStrings(this.translatedSet...)
-	theText := regexp.MustCompile(`\s*\[\]\s*`).ReplaceAllString(this.translatedText, " ")
+	token := regexp.QuoteMeta(this.alignOptions.emptyLineToken())
+	theText := regexp.MustCompile(`(?:\s*`+token+`)+\s*`).ReplaceAllString(this.translatedText, " ")
+	// Spaces next to chars of scripts written without spaces do not count,
+	// nor those left at the ends by an empty line token
+	theTextLines = strings.TrimSpace(removeSpacesInNoSpaceScript(theTextLines))
+	theTextSets = strings.TrimSpace(removeSpacesInNoSpaceScript(theTextSets))
+	theText = strings.TrimSpace(removeSpacesInNoSpaceScript(theText))
 	if !strings.EqualFold(theTextLines, theTextSets) {
 		return false
 	}
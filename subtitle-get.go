@@ -4,38 +4,38 @@ package subtitle
 // Extract different data from SubtitleSRT
 // ----------------------------------------
 
-// Get the original text, returns text and length (runes)
+// Get the original text, returns text and length (chars)
 //
 func (this *SubtitleSRT) GetOriginalText() (string, int) {
-	theText := prepareString(JoinAllLines(this.originalLine...))
-	return theText, len([]rune(theText))
+	theText := this.normalizeOriginal(JoinAllLines(this.originalLine...))
+	return theText, countGraphemes(theText)
 }
 
-// Get the translated text, returns text and length (runes)
+// Get the translated text, returns text and length (chars)
 //
 func (this *SubtitleSRT) GetTranslatedText() (string, int) {
 	theText := this.translatedText
-	return theText, len([]rune(theText))
+	return theText, countGraphemes(theText)
 }
 
 // Get the original text of a given line set
-// Returns text and length (runes)
+// Returns text and length (chars)
 func (this *SubtitleSRT) GetOriginalTextOfLineSet(ls int) (string, int) {
 	if ls >= len(this.lineSet) || ls < 0 {
 		return "", -1
 	}
 	theText := joinStrings(this.originalLine[this.lineSet[ls].InitLine : this.lineSet[ls].LastLine+1]...)
-	return theText, len([]rune(theText))
+	return theText, countGraphemes(theText)
 }
 
 // Get the translated text of a given line set
-// Returns text and length (runes)
+// Returns text and length (chars)
 func (this *SubtitleSRT) GetTranslatedTextOfLineSet(ls int) (string, int) {
 	if ls >= len(this.lineSet) || ls < 0 {
 		return "", -1
 	}
 	theText := this.translatedSet[ls]
-	return theText, len([]rune(theText))
+	return theText, countGraphemes(theText)
 }
 
 // Get the original lines array
@@ -50,7 +50,22 @@ func (this *SubtitleSRT) GetTranslatedLines() []string {
 	return this.translatedLine
 }
 
+// GetSubtitleBlocks returns the subtitle blocks in SubtitleSRT
+func (this *SubtitleSRT) GetSubtitleBlocks() []SubtitleBlock {
+	return this.subtitleBlock
+}
+
 // GetLineSet returns the LineSet definition in SubtitleSRT
 func (this *SubtitleSRT) GetLineSets() []LineSet {
 	return this.lineSet
 }
+
+// GetAlignStrategy returns the strategy used to split the text into LineSets
+func (this *SubtitleSRT) GetAlignStrategy() AlignStrategy {
+	return this.alignStrategy
+}
+
+// GetAlignOptions returns the options used to split the text into LineSets
+func (this *SubtitleSRT) GetAlignOptions() AlignOptions {
+	return this.alignOptions
+}
@@ -0,0 +1,265 @@
+package subtitle
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ------------------------------------------------------
+// Undo and redo of the edits of the blocks, lines, LineSet:s and options
+// ------------------------------------------------------
+
+// Default max number of undo steps
+const defaultHistoryDepth = 100
+
+// An historyStep is an edit that can be undone or redone
+//   - name is the operation and its arguments
+//   - state holds the blocks, lines, LineSet:s, options and metadata
+//     before (undo) or after (redo) it
+type historyStep struct {
+	name  string
+	state *SubtitleSRT
+}
+
+// editHistory keeps the undo and redo steps
+//   - depth is the max number of undo steps (0 means the default, <0 no history)
+//   - nesting is the number of edits running, only the outer one is a step
+//   - before is the state when the outer edit or the group started
+//   - group is the name of the group started by BeginGroup, if any
+type editHistory struct {
+	undo    []historyStep
+	redo    []historyStep
+	depth   int
+	nesting int
+	before  *SubtitleSRT
+	group   string
+	inGroup bool
+}
+
+// SetHistoryDepth sets the max number of undo steps
+// 0 selects the default (100), a negative depth disables the history
+func (this *SubtitleSRT) SetHistoryDepth(depth int) {
+	this.journalCall("SetHistoryDepth", depth)
+	this.history.depth = depth
+	this.history.trim()
+}
+
+// GetHistoryDepth returns the max number of undo steps
+func (this *SubtitleSRT) GetHistoryDepth() int {
+	return this.history.getDepth()
+}
+
+// getDepth returns depth or its default
+func (this *editHistory) getDepth() int {
+	switch {
+	case this.depth == 0:
+		return defaultHistoryDepth
+	case this.depth < 0:
+		return 0
+	}
+	return this.depth
+}
+
+// trim removes the oldest undo steps over the depth
+func (this *editHistory) trim() {
+	if over := len(this.undo) - this.getDepth(); over > 0 {
+		this.undo = append([]historyStep(nil), this.undo[over:]...)
+	}
+}
+
+// snapshot returns a copy of the blocks, lines, LineSet:s, options and metadata
+func (this *SubtitleSRT) snapshot() *SubtitleSRT {
+	lineSet := append([]LineSet(nil), this.lineSet...)
+	for i := range lineSet {
+		lineSet[i].Comments = append([]Comment(nil), lineSet[i].Comments...)
+	}
+	return &SubtitleSRT{
+		subtitleBlock:  append([]SubtitleBlock(nil), this.subtitleBlock...),
+		lineSet:        lineSet,
+		originalLine:   append([]string(nil), this.originalLine...),
+		translatedLine: append([]string(nil), this.translatedLine...),
+		translatedSet:  append([]string(nil), this.translatedSet...),
+		translatedText: this.translatedText,
+		alignStrategy:  this.alignStrategy,
+		alignOptions:   this.alignOptions,
+		splitOptions:   this.splitOptions,
+		rtlOptions:     this.rtlOptions,
+		metadata:       this.metadata,
+	}
+}
+
+// restore sets the blocks, lines, LineSet:s, options and metadata of a snapshot
+func (this *SubtitleSRT) restore(state *SubtitleSRT) {
+	s := state.snapshot()
+	this.subtitleBlock = s.subtitleBlock
+	this.lineSet = s.lineSet
+	this.originalLine = s.originalLine
+	this.translatedLine = s.translatedLine
+	this.translatedSet = s.translatedSet
+	this.translatedText = s.translatedText
+	this.alignStrategy = s.alignStrategy
+	this.alignOptions = s.alignOptions
+	this.splitOptions = s.splitOptions
+	this.rtlOptions = s.rtlOptions
+	this.metadata = s.metadata
+}
+
+// sameState returns true if the SubtitleSRT has the blocks, lines, LineSet:s,
+// options and metadata of a snapshot, but for the modification time
+func (this *SubtitleSRT) sameState(state *SubtitleSRT) bool {
+	metadata, other := this.metadata, state.metadata
+	metadata.Modified, other.Modified = time.Time{}, time.Time{}
+	return this.IsEqual(*state) && metadata == other &&
+		this.alignStrategy == state.alignStrategy && this.alignOptions == state.alignOptions &&
+		this.splitOptions == state.splitOptions && this.rtlOptions == state.rtlOptions
+}
+
+// beginEdit starts an edit, and returns the function that ends it with
+// the error returned by the edit, or nil if it does not return one:
+//
+//	defer this.beginEdit("Operation", args...)(&err)
+//
+// An edit that changes something is an undo step, and clears the redo steps
+// The edits made by another edit, or in a group, are part of its step
+// The outer edits are written to the journal, and set the modification time
+// if they change something. The edits that fail do neither
+func (this *SubtitleSRT) beginEdit(name string, args ...interface{}) func(err *error) {
+	h := &this.history
+	var before *SubtitleSRT
+	if h.nesting == 0 {
+		before = this.snapshot()
+		if !h.inGroup && h.getDepth() > 0 {
+			h.before = before
+		}
+	}
+	h.nesting++
+	return func(err *error) {
+		h.nesting--
+		if h.nesting > 0 {
+			return
+		}
+		if err == nil || *err == nil {
+			if !this.sameState(before) {
+				this.touchModified()
+			}
+			this.journalEdit(name, args...)
+		}
+		if !h.inGroup {
+			this.pushStep(editName(name, args...))
+		}
+	}
+}
+
+// pushStep adds an undo step with the state before the edit,
+// if the edit changed something
+func (this *SubtitleSRT) pushStep(name string) {
+	h := &this.history
+	before := h.before
+	h.before = nil
+	if before == nil || this.sameState(before) {
+		return
+	}
+	h.undo = append(h.undo, historyStep{name, before})
+	h.redo = nil
+	h.trim()
+}
+
+// editName returns the name of an edit and its arguments, as a call
+func editName(name string, args ...interface{}) string {
+	list := make([]string, len(args))
+	for i, arg := range args {
+		list[i] = fmt.Sprintf("%v", arg)
+	}
+	return name + "(" + strings.Join(list, ", ") + ")"
+}
+
+// BeginGroup starts a group of edits that are undone as a single step
+// Groups cannot be nested, a group is ended by EndGroup
+func (this *SubtitleSRT) BeginGroup(name string) {
+	h := &this.history
+	if h.inGroup || h.nesting > 0 {
+		return
+	}
+	this.journalCall("BeginGroup", name)
+	h.inGroup, h.group = true, name
+	if h.getDepth() > 0 {
+		h.before = this.snapshot()
+	}
+}
+
+// EndGroup ends the group of edits started by BeginGroup
+func (this *SubtitleSRT) EndGroup() {
+	h := &this.history
+	if !h.inGroup || h.nesting > 0 {
+		return
+	}
+	this.journalCall("EndGroup")
+	h.inGroup = false
+	this.pushStep(h.group)
+}
+
+// CanUndo returns true if there is an edit to undo
+func (this *SubtitleSRT) CanUndo() bool {
+	return len(this.history.undo) > 0
+}
+
+// CanRedo returns true if there is an edit to redo
+func (this *SubtitleSRT) CanRedo() bool {
+	return len(this.history.redo) > 0
+}
+
+// GetUndoNames returns the edits that can be undone, the last one the first
+func (this *SubtitleSRT) GetUndoNames() []string {
+	return historyNames(this.history.undo)
+}
+
+// GetRedoNames returns the edits that can be redone, the next one the first
+func (this *SubtitleSRT) GetRedoNames() []string {
+	return historyNames(this.history.redo)
+}
+
+// historyNames returns the names of the steps, the last one the first
+func historyNames(steps []historyStep) []string {
+	names := make([]string, len(steps))
+	for i, step := range steps {
+		names[len(steps)-1-i] = step.name
+	}
+	return names
+}
+
+// Undo reverts the last edit, returns false if there is none
+func (this *SubtitleSRT) Undo() bool {
+	h := &this.history
+	if len(h.undo) == 0 || h.nesting > 0 || h.inGroup {
+		return false
+	}
+	this.journalCall("Undo")
+	step := h.undo[len(h.undo)-1]
+	h.undo = h.undo[:len(h.undo)-1]
+	h.redo = append(h.redo, historyStep{step.name, this.snapshot()})
+	this.restore(step.state)
+	this.touchModified()
+	return true
+}
+
+// Redo makes again the last edit undone, returns false if there is none
+func (this *SubtitleSRT) Redo() bool {
+	h := &this.history
+	if len(h.redo) == 0 || h.nesting > 0 || h.inGroup {
+		return false
+	}
+	this.journalCall("Redo")
+	step := h.redo[len(h.redo)-1]
+	h.redo = h.redo[:len(h.redo)-1]
+	h.undo = append(h.undo, historyStep{step.name, this.snapshot()})
+	this.restore(step.state)
+	this.touchModified()
+	return true
+}
+
+// ClearHistory removes all the undo and redo steps
+func (this *SubtitleSRT) ClearHistory() {
+	this.history.undo = nil
+	this.history.redo = nil
+}
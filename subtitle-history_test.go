@@ -0,0 +1,93 @@
+package subtitle
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUndoRedo(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, StatisticalAlignment)
+	subt.ClearHistory()
+	original := *subt.snapshot()
+
+	subt.MergeLineSetWithNext(0)
+	merged := *subt.snapshot()
+	subt.MoveWordFromLineToNext(0)
+	if !subt.CanUndo() || subt.CanRedo() || strings.Join(subt.GetUndoNames(), "|") != "MoveWordFromLineToNext(0)|MergeLineSetWithNext(0)" {
+		t.Fatalf("GetUndoNames(): unexpected %q", subt.GetUndoNames())
+	}
+
+	subt.Undo()
+	if !subt.IsEqual(merged) {
+		t.Fatal("Undo(): MoveWordFromLineToNext not undone")
+	}
+	subt.Undo()
+	if !subt.IsEqual(original) || subt.CanUndo() || subt.Undo() {
+		t.Fatal("Undo(): MergeLineSetWithNext not undone")
+	}
+	subt.Redo()
+	if !subt.IsEqual(merged) || strings.Join(subt.GetRedoNames(), "|") != "MoveWordFromLineToNext(0)" {
+		t.Fatalf("Redo(): unexpected redo %q", subt.GetRedoNames())
+	}
+
+	// A new edit clears the redo steps, an edit that changes nothing is no step
+	subt.SplitLineSetByLine(0, 1)
+	subt.MergeLineSetWithPrev(0)
+	if subt.CanRedo() || len(subt.GetUndoNames()) != 2 {
+		t.Fatalf("SplitLineSetByLine(): unexpected undo %q", subt.GetUndoNames())
+	}
+}
+
+func TestUndoGroupAndDepth(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, StatisticalAlignment)
+	subt.ClearHistory()
+	original := *subt.snapshot()
+
+	// The edits of InsertBlock and the group are a single step
+	subt.BeginGroup("Rework")
+	subt.InsertBlock(1, "00:00:03,100 --> 00:00:03,400", "Hello.")
+	subt.MergeBlocks(0)
+	subt.EndGroup()
+	if names := subt.GetUndoNames(); len(names) != 1 || names[0] != "Rework" {
+		t.Fatalf("EndGroup(): unexpected undo %q", names)
+	}
+	subt.Undo()
+	if !subt.IsEqual(original) {
+		t.Fatal("Undo(): group not undone")
+	}
+
+	subt.SetHistoryDepth(2)
+	for i := 0; i < 3; i++ {
+		subt.ShiftBlocks(time.Second)
+	}
+	if len(subt.GetUndoNames()) != 2 || subt.GetHistoryDepth() != 2 {
+		t.Fatalf("SetHistoryDepth(): unexpected undo %q", subt.GetUndoNames())
+	}
+	subt.SetHistoryDepth(-1)
+	subt.ShiftBlocks(time.Second)
+	if len(subt.GetUndoNames()) != 0 {
+		t.Fatalf("SetHistoryDepth(): unexpected undo %q", subt.GetUndoNames())
+	}
+}
+
+func TestUndoOptionsAndComments(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, StatisticalAlignment)
+	subt.ClearHistory()
+	subt.SetSplitOptions(SplitOptions{MaxCharsPerLine: 10})
+	subt.SetTargetLanguage("es")
+	subt.Undo()
+	subt.Undo()
+	if subt.GetSplitOptions() != (SplitOptions{}) || subt.GetTargetLanguage() != "" || subt.CanUndo() {
+		t.Fatalf("Undo(): options not undone %+v %+v", subt.GetSplitOptions(), subt.GetMetadata())
+	}
+
+	// The comments of the undo steps are not shared with the LineSet:s
+	subt.AddLineSetComment(0, Comment{Author: "ana", Text: "First"})
+	subt.SetLineSetStatus(0, StatusReviewed)
+	subt.lineSet[0].Comments[0].Text = "Changed"
+	subt.Undo()
+	if c := subt.lineSet[0].Comments; len(c) != 1 || c[0].Text != "First" {
+		t.Fatalf("Undo(): unexpected comments %+v", c)
+	}
+}
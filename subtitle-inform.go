@@ -1,6 +1,9 @@
 package subtitle
 
-import "strings"
+import (
+	"math"
+	"sort"
+)
 
 // --------------------------------------------
 // Informative functions about the SubtitleSRT
@@ -36,6 +39,11 @@ func (this *SubtitleSRT) CountLineSets() int {
 	return len(this.lineSet)
 }
 
+// CountBlocks returns the total number of subtitle blocks in SubtitleSRT
+func (this *SubtitleSRT) CountBlocks() int {
+	return len(this.subtitleBlock)
+}
+
 // CountLines returns the total number of lines in SubtitleSRT
 func (this *SubtitleSRT) CountLines() int {
 	return len(this.originalLine)
@@ -44,7 +52,7 @@ func (this *SubtitleSRT) CountLines() int {
 // CountOriginalWords returns the total number of original words in SubtitleSRT
 func (this *SubtitleSRT) CountOriginalWords() int {
 	originalText, _ := this.GetOriginalText()
-	return len(strings.Fields(originalText))
+	return countWords(originalText)
 }
 
 // Count lines in a given line set
@@ -62,13 +70,13 @@ func (this *SubtitleSRT) CountOriginalWordsInLine(theLine int) int {
 		return -1
 	}
 
-	return len(strings.Fields(this.originalLine[theLine]))
+	return countWords(this.originalLine[theLine])
 }
 
 // CountOriginalChars returns the original chars in SubtitleSRT
 func (this *SubtitleSRT) CountOriginalChars() int {
 	originalText, _ := this.GetOriginalText()
-	return len([]rune(originalText)) - len(this.originalLine) + 1
+	return countJoinedChars(originalText, len(this.originalLine))
 }
 
 // Count original chars in a given line
@@ -77,7 +85,7 @@ func (this *SubtitleSRT) CountOriginalCharsInLine(theLine int) int {
 		return -1
 	}
 
-	return len([]rune(this.originalLine[theLine]))
+	return countChars(this.originalLine[theLine])
 }
 
 // Count original words in a given line set
@@ -87,10 +95,10 @@ func (this *SubtitleSRT) CountOriginalWordsInLineSet(theLineSet int) int {
 	}
 
 	text, _ := this.GetOriginalTextOfLineSet(theLineSet)
-	return len(strings.Fields(text))
+	return countWords(text)
 }
 
-// Count original chars (runes) in a given line set
+// Count original chars in a given line set
 func (this *SubtitleSRT) CountOriginalCharsInLineSet(theLineSet int) int {
 	if theLineSet >= len(this.lineSet) || theLineSet < 0 {
 		return -1
@@ -104,7 +112,7 @@ func (this *SubtitleSRT) CountOriginalCharsInLineSet(theLineSet int) int {
 
 // CountTranslatedWords returns the number of translated words in a SubtitleSRT
 func (this *SubtitleSRT) CountTranslatedWords() int {
-	return len(strings.Fields(this.translatedText))
+	return countWords(this.translatedText)
 }
 
 // Count translated words in a given line set
@@ -113,21 +121,110 @@ func (this *SubtitleSRT) CountTranslatedWordsInLineSet(theLineSet int) int {
 		return -1
 	}
 
-	return len(strings.Fields(this.translatedSet[theLineSet]))
+	return countWords(this.translatedSet[theLineSet])
 }
 
-// CountTranslatedChars returns translated chars (runes) in a SubtitleSRT
+// CountTranslatedChars returns translated chars in a SubtitleSRT
 func (this *SubtitleSRT) CountTranslatedChars() int {
 	// Translated Chars is number of chars - CRLF (number of lines + 1)
-	return len([]rune(this.translatedText)) - len(this.originalLine) + 1
+	return countJoinedChars(this.translatedText, len(this.originalLine))
 }
 
-// Count translated chars (runes) in a given line set
+// Count translated chars in a given line set
 func (this *SubtitleSRT) CountTranslatedCharsInLineSet(theLineSet int) int {
 	if theLineSet >= len(this.lineSet) || theLineSet < 0 {
 		return -1
 	}
 
-	// NUmber of Chars is total runes - CRLFs (****) more precise: CRLFs - empty lines
-	return len([]rune(this.translatedSet[theLineSet])) - (this.lineSet[theLineSet].LastLine - this.lineSet[theLineSet].InitLine)
+	// NUmber of Chars is total chars - CRLFs (****) more precise: CRLFs - empty lines
+	return countJoinedChars(this.translatedSet[theLineSet], this.lineSet[theLineSet].LastLine-this.lineSet[theLineSet].InitLine+1)
+}
+
+// LineSetConfidence is the alignment confidence of a LineSet
+//   * Exact is true if the LineSet was detected by an exact match
+//   * Ratio is translatedChars/originalChars of the LineSet
+//   * RatioDeviation is the relative deviation of Ratio from the median ratio (signed)
+//   * Lines is the number of lines of the LineSet
+//   * Score goes from 0 (surely wrong) to 1 (surely right)
+type LineSetConfidence struct {
+	LineSet        int
+	Exact          bool
+	Ratio          float64
+	RatioDeviation float64
+	Lines          int
+	Score          float64
+}
+
+// CalculateMedianRatio returns the median of the ratio translatedChars/originalChars
+// of all the LineSet:s with original and translated text
+func (this *SubtitleSRT) CalculateMedianRatio() float64 {
+	var ratios []float64
+	for i := range this.lineSet {
+		if this.CountOriginalCharsInLineSet(i) > 0 && this.CountTranslatedCharsInLineSet(i) > 0 {
+			ratios = append(ratios, this.CalculateRatioOfLineSet(i))
+		}
+	}
+	if len(ratios) == 0 {
+		return 0
+	}
+	sort.Float64s(ratios)
+	if len(ratios)%2 == 1 {
+		return ratios[len(ratios)/2]
+	}
+	return (ratios[len(ratios)/2-1] + ratios[len(ratios)/2]) / 2
+}
+
+// CalculateConfidenceOfLineSet returns the alignment confidence of a LineSet
+// The Score is the product of:
+//   * 1 if the LineSet is exact, 0.5/sqrt(lines) if it has been inferred
+//     (the longer an inferred LineSet, the more likely it hides a bad split)
+//   * min(ratio/median, median/ratio), 0 if the translation is missing
+func (this *SubtitleSRT) CalculateConfidenceOfLineSet(theLineSet int) LineSetConfidence {
+	if theLineSet >= len(this.lineSet) || theLineSet < 0 {
+		return LineSetConfidence{LineSet: -1}
+	}
+	return this.calculateConfidence(theLineSet, this.CalculateMedianRatio())
+}
+
+// GetLineSetsByConfidence returns the confidence of all the LineSet:s
+// sorted by ascending Score, so that the riskiest ones come first
+func (this *SubtitleSRT) GetLineSetsByConfidence() []LineSetConfidence {
+	median := this.CalculateMedianRatio()
+	queue := make([]LineSetConfidence, len(this.lineSet))
+	for i := range this.lineSet {
+		queue[i] = this.calculateConfidence(i, median)
+	}
+	sort.SliceStable(queue, func(i, j int) bool {
+		return queue[i].Score < queue[j].Score
+	})
+	return queue
+}
+
+// calculateConfidence returns the confidence of a LineSet given the median ratio
+func (this *SubtitleSRT) calculateConfidence(theLineSet int, median float64) LineSetConfidence {
+	c := LineSetConfidence{
+		LineSet: theLineSet,
+		Exact:   this.lineSet[theLineSet].Exact,
+		Ratio:   this.CalculateRatioOfLineSet(theLineSet),
+		Lines:   this.CountLinesInLineSet(theLineSet),
+	}
+
+	// Exact match vs inferred
+	c.Score = 1.0
+	if !c.Exact {
+		c.Score = 0.5 / math.Sqrt(float64(c.Lines))
+	}
+
+	// Deviation from the median ratio
+	switch {
+	case this.CountOriginalCharsInLineSet(theLineSet) == 0 || median == 0:
+		// Nothing to compare with
+	case c.Ratio <= 0:
+		c.RatioDeviation = -1
+		c.Score = 0
+	default:
+		c.RatioDeviation = (c.Ratio - median) / median
+		c.Score *= math.Min(c.Ratio/median, median/c.Ratio)
+	}
+	return c
 }
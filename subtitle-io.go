@@ -3,11 +3,12 @@ package subtitle
 import (
 	"encoding/json"
 	"io/ioutil"
+	"path/filepath"
 )
 
 // Read from/Write to JSON files
 
-// writeToFile writes a SubtitleSRT struct into JSON files
+// WriteToFile writes a SubtitleSRT struct into JSON files
 // Each field will be saved in a separate file
 //    SubtitleBlock -> subtitleblock.json
 //    LineSet -> lineset.json
@@ -15,6 +16,8 @@ import (
 //    translatedLine -> translatedline.json
 //    translatedSet -> translatedset.json
 //    translatedText -> translatedtext.json
+//
+// Deprecated: the files are written in the current directory, use Save
 func (this *SubtitleSRT) WriteToFile() error {
 	var data []byte
 	var err error
@@ -82,77 +85,39 @@ func (this *SubtitleSRT) WriteToFile() error {
 	return nil
 }
 
-// writeToFile writes a SubtitleSRT struct into JSON files
-// Each field will be saved in a separate file
+// ReadFromFile reads a SubtitleSRT struct from the JSON files of WriteToFile
+// in the current directory
+//
+// Deprecated: use Load, ReadFromFile is kept to migrate the old projects
+func (this *SubtitleSRT) ReadFromFile() error {
+	return this.LoadLegacyFiles(".")
+}
+
+// LoadLegacyFiles reads a SubtitleSRT struct from the JSON files of
+// WriteToFile in a directory, as a project document of version 1
 //    SubtitleBlock <- subtitleblock.json
 //    LineSet <- lineset.json
 //    originalLine <- originalline.json
 //    translatedLine <- translatedline.json
 //    translatedSet <- translatedset.json
 //    translatedText <- translatedtext.json
-func (this *SubtitleSRT) ReadFromFile() error {
-	var data []byte
-	var err error
-
-	// Read SubtitleBlock
-	data, err = ioutil.ReadFile("subtitleblock.json")
-	if err != nil {
-		return err
-	}
-	err = json.Unmarshal(data, &this.subtitleBlock)
-	if err != nil {
-		return err
-	}
-
-	// Read LineSet
-	data, err = ioutil.ReadFile("lineset.json")
-	if err != nil {
-		return err
-	}
-	err = json.Unmarshal(data, &this.lineSet)
-	if err != nil {
-		return err
-	}
-
-	// Read originalLine
-	data, err = ioutil.ReadFile("originalline.json")
-	if err != nil {
-		return err
+func (this *SubtitleSRT) LoadLegacyFiles(dir string) error {
+	doc := make(map[string]json.RawMessage)
+	for _, name := range legacyFiles {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name+".json"))
+		if err != nil {
+			return err
+		}
+		doc[name] = data
 	}
-	err = json.Unmarshal(data, &this.originalLine)
-	if err != nil {
-		return err
-	}
-
-	// Read translatedLine
-	data, err = ioutil.ReadFile("translatedline.json")
-	if err != nil {
-		return err
-	}
-	err = json.Unmarshal(data, &this.translatedLine)
-	if err != nil {
-		return err
-	}
-
-	// Read translatedSet
-	data, err = ioutil.ReadFile("translatedset.json")
-	if err != nil {
-		return err
-	}
-	err = json.Unmarshal(data, &this.translatedSet)
-	if err != nil {
-		return err
-	}
-
-	// Read translatedText
-	data, err = ioutil.ReadFile("translatedtext.json")
-	if err != nil {
-		return err
-	}
-	err = json.Unmarshal(data, &this.translatedText)
+	data, err := json.Marshal(doc)
 	if err != nil {
 		return err
 	}
+	return this.UnmarshalJSON(data)
+}
 
-	return nil
+// The files of WriteToFile, without the .json extension
+var legacyFiles = []string{
+	"subtitleblock", "lineset", "originalline", "translatedline", "translatedset", "translatedtext",
 }
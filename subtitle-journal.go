@@ -0,0 +1,327 @@
+package subtitle
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"time"
+)
+
+// ------------------------------------------------------
+// Journal of the edits, to audit and to replay them
+// ------------------------------------------------------
+
+// A JournalEntry is a call that changed the SubtitleSRT, a JSON line
+// of the journal. Op is the name of the method, Args its arguments
+type JournalEntry struct {
+	Time   time.Time         `json:"time"`
+	Author string            `json:"author,omitempty"`
+	Op     string            `json:"op"`
+	Args   []json.RawMessage `json:"args,omitempty"`
+}
+
+// journalWriter writes the journal entries
+//   - err is the first error writing the journal, no entry is written after it
+type journalWriter struct {
+	writer io.Writer
+	author string
+	err    error
+}
+
+// The methods written to the journal, and replayed
+var journalOps = map[string]bool{
+	"SetTranslatedTextWithOptions": true,
+	"SetTranslatedTextOfLineSet":   true,
+	"SetAlignStrategy":             true,
+	"SetSplitOptions":              true,
+	"SetSourceLanguage":            true,
+	"SetTargetLanguage":            true,
+	"SetRTLOptions":                true,
+	"SetMetadata":                  true,
+	"SetLineSplitter":              true,
+	"SetNormalizers":               true,
+	"MoveLinesFromLineSetToPrev":   true,
+	"MoveWordsFromLineSetToPrev":   true,
+	"MoveLinesFromLineSetToNext":   true,
+	"MoveWordsFromLineSetToNext":   true,
+	"MoveWordFromLineToPrev":       true,
+	"MoveWordFromLineToNext":       true,
+	"SplitLineSetByLine":           true,
+	"MergeLineSetWithPrev":         true,
+	"MergeLineSetWithNext":         true,
+	"SetLineSetStatus":             true,
+	"LockLineSet":                  true,
+	"UnlockLineSet":                true,
+	"AddLineSetComment":            true,
+	"FindReplace":                  true,
+	"InsertBlock":                  true,
+	"DeleteBlock":                  true,
+	"SplitBlock":                   true,
+	"MergeBlocks":                  true,
+	"NormalizeBlocks":              true,
+	"ShiftBlocks":                  true,
+	"ShiftBlockRange":              true,
+	"ScaleTimes":                   true,
+	"ScaleTimeRange":               true,
+	"SyncByTwoPoints":              true,
+	"EnforceTiming":                true,
+	"SnapToShotChanges":            true,
+	"ApplyAudioSync":               true,
+	"ResegmentLineSetByDuration":   true,
+	"ApplyBoundaryProposal":        true,
+	"SetHistoryDepth":              true,
+	"BeginGroup":                   true,
+	"EndGroup":                     true,
+	"Undo":                         true,
+	"Redo":                         true,
+}
+
+// The calls that start a journal, setting the configuration
+var journalConfigOps = []string{"SetAlignStrategy", "SetSplitOptions", "SetRTLOptions", "SetMetadata", "SetLineSplitter", "SetNormalizers"}
+
+// SetJournal writes every call that changes the SubtitleSRT to a writer,
+// as JSON lines with the time and the author. A nil writer stops the journal
+// The journal starts with the calls that set the current configuration
+func (this *SubtitleSRT) SetJournal(writer io.Writer, author string) {
+	if writer == nil {
+		this.journal = nil
+		return
+	}
+	this.journal = &journalWriter{writer: writer, author: author}
+	this.journalEdit("SetAlignStrategy", this.alignStrategy)
+	this.journalEdit("SetSplitOptions", this.splitOptions)
+	this.journalEdit("SetRTLOptions", this.rtlOptions)
+	this.journalEdit("SetMetadata", this.metadata)
+	this.journalEdit("SetLineSplitter", newJournalPlugin(this.lineSplitter))
+	this.journalEdit("SetNormalizers", newJournalPlugin(this.originalNormalizer), newJournalPlugin(this.translatedNormalizer))
+}
+
+// A journalPlugin is a line splitter or a normalizer in the journal: its
+// type and its configuration. The zero value is the default one
+type journalPlugin struct {
+	Type   string          `json:"type,omitempty"`
+	Config json.RawMessage `json:"config,omitempty"`
+}
+
+// The line splitters and the normalizers that Replay creates again
+var journalPluginTypes = map[string]func(config []byte) (interface{}, error){
+	fmt.Sprintf("%T", ProportionalSplitter{}): func(config []byte) (interface{}, error) {
+		var splitter ProportionalSplitter
+		err := json.Unmarshal(config, &splitter)
+		return splitter, err
+	},
+	fmt.Sprintf("%T", &OptimalSplitter{}): func(config []byte) (interface{}, error) {
+		splitter := &OptimalSplitter{}
+		err := json.Unmarshal(config, splitter)
+		return splitter, err
+	},
+	fmt.Sprintf("%T", TextNormalizer{}): func(config []byte) (interface{}, error) {
+		var normalizer TextNormalizer
+		err := json.Unmarshal(config, &normalizer)
+		return normalizer, err
+	},
+}
+
+// newJournalPlugin returns the journalPlugin of a line splitter or a normalizer
+// A configuration that is not JSON is left out
+func newJournalPlugin(plugin interface{}) journalPlugin {
+	if plugin == nil {
+		return journalPlugin{}
+	}
+	config, _ := json.Marshal(plugin)
+	return journalPlugin{Type: fmt.Sprintf("%T", plugin), Config: config}
+}
+
+// replayPlugins sets the line splitter or the normalizers of a journal entry
+// The ones defined outside the package cannot be created again: they must
+// be the ones in use or in available, or the replay fails
+func (this *SubtitleSRT) replayPlugins(entry JournalEntry, available []interface{}) error {
+	current := []interface{}{this.lineSplitter}
+	if entry.Op == "SetNormalizers" {
+		current = []interface{}{this.originalNormalizer, this.translatedNormalizer}
+	}
+	if len(entry.Args) != len(current) {
+		return fmt.Errorf("%s takes %d arguments, not %d", entry.Op, len(current), len(entry.Args))
+	}
+	plugins := make([]interface{}, len(current))
+	for i, data := range entry.Args {
+		var plugin journalPlugin
+		if err := json.Unmarshal(data, &plugin); err != nil {
+			return fmt.Errorf("argument %d of %s: %v", i+1, entry.Op, err)
+		}
+		create, ok := journalPluginTypes[plugin.Type]
+		switch {
+		case plugin.Type == "":
+		case ok:
+			created, err := create(plugin.Config)
+			if err != nil {
+				return fmt.Errorf("argument %d of %s: %v", i+1, entry.Op, err)
+			}
+			plugins[i] = created
+		default:
+			for _, candidate := range append([]interface{}{current[i]}, available...) {
+				inUse := newJournalPlugin(candidate)
+				if inUse.Type == plugin.Type && bytes.Equal(inUse.Config, plugin.Config) {
+					plugins[i] = candidate
+					break
+				}
+			}
+			if plugins[i] == nil {
+				return fmt.Errorf("%s: %s of the journal is not in use", entry.Op, plugin.Type)
+			}
+		}
+	}
+
+	if entry.Op == "SetNormalizers" {
+		original, _ := plugins[0].(Normalizer)
+		translated, _ := plugins[1].(Normalizer)
+		this.SetNormalizers(original, translated)
+	} else {
+		splitter, _ := plugins[0].(LineSplitter)
+		this.SetLineSplitter(splitter)
+	}
+	return nil
+}
+
+// GetJournalError returns the first error writing the journal, if any
+func (this *SubtitleSRT) GetJournalError() error {
+	if this.journal == nil {
+		return nil
+	}
+	return this.journal.err
+}
+
+// journalEdit writes an edit to the journal
+func (this *SubtitleSRT) journalEdit(op string, args ...interface{}) {
+	j := this.journal
+	if j == nil || j.err != nil {
+		return
+	}
+	entry := JournalEntry{Time: time.Now().UTC(), Author: j.author, Op: op}
+	for _, arg := range args {
+		data, err := json.Marshal(arg)
+		if err != nil {
+			j.err = err
+			return
+		}
+		entry.Args = append(entry.Args, data)
+	}
+	data, err := json.Marshal(entry)
+	if err == nil {
+		_, err = j.writer.Write(append(data, '\n'))
+	}
+	j.err = err
+}
+
+// journalCall writes a call to the journal, if it is not made by an edit
+func (this *SubtitleSRT) journalCall(op string, args ...interface{}) {
+	if this.history.nesting == 0 {
+		this.journalEdit(op, args...)
+	}
+}
+
+// ReadJournal reads the entries of a journal
+func ReadJournal(reader io.Reader) ([]JournalEntry, error) {
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(nil, 64*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry JournalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("subtitle: line %d of the journal: %v", n, err)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+// Replay clears the SubtitleSRT and its configuration, imports the original
+// SRT and makes again the calls of a journal. The line splitters and the
+// normalizers defined outside the package must be set as when the journal
+// was written
+// The calls replayed are not written to the journal
+func (this *SubtitleSRT) Replay(srt io.Reader, journal io.Reader) error {
+	entries, err := ReadJournal(journal)
+	if err != nil {
+		return err
+	}
+	j := this.journal
+	this.journal = nil
+	defer func() { this.journal = j }()
+
+	// The line splitter and the normalizers set by the caller are kept
+	available := []interface{}{this.lineSplitter, this.originalNormalizer, this.translatedNormalizer}
+	this.DeleteSubtitleSrt()
+	this.alignStrategy = ExactMatchAlignment
+	this.alignOptions = AlignOptions{}
+	this.splitOptions = SplitOptions{}
+	this.rtlOptions = RTLOptions{}
+	this.metadata = Metadata{}
+	this.SetOriginalSrt(srt)
+	// The configuration the journal starts with is not an edit to undo
+	config := 0
+	for config < len(journalConfigOps) && config < len(entries) && entries[config].Op == journalConfigOps[config] {
+		config++
+	}
+	if config < len(journalConfigOps) {
+		config = 0
+	}
+	for n, entry := range entries {
+		if err := this.replayEntry(entry, available); err != nil {
+			return fmt.Errorf("subtitle: entry %d of the journal: %v", n+1, err)
+		}
+		if n+1 == config {
+			this.ClearHistory()
+		}
+	}
+	return nil
+}
+
+// ReplayEntry makes again the call of a journal entry
+func (this *SubtitleSRT) ReplayEntry(entry JournalEntry) error {
+	return this.replayEntry(entry, nil)
+}
+
+// replayEntry makes again the call of a journal entry, with the line
+// splitters and the normalizers available besides the ones in use
+func (this *SubtitleSRT) replayEntry(entry JournalEntry, available []interface{}) error {
+	if !journalOps[entry.Op] {
+		return fmt.Errorf("unknown operation %q", entry.Op)
+	}
+	if entry.Op == "SetLineSplitter" || entry.Op == "SetNormalizers" {
+		return this.replayPlugins(entry, available)
+	}
+	method := reflect.ValueOf(this).MethodByName(entry.Op)
+	mtype := method.Type()
+	if len(entry.Args) != mtype.NumIn() {
+		return fmt.Errorf("%s takes %d arguments, not %d", entry.Op, mtype.NumIn(), len(entry.Args))
+	}
+	args := make([]reflect.Value, len(entry.Args))
+	for i, data := range entry.Args {
+		arg := reflect.New(mtype.In(i))
+		if err := json.Unmarshal(data, arg.Interface()); err != nil {
+			return fmt.Errorf("argument %d of %s: %v", i+1, entry.Op, err)
+		}
+		args[i] = arg.Elem()
+	}
+
+	var results []reflect.Value
+	if mtype.IsVariadic() {
+		results = method.CallSlice(args)
+	} else {
+		results = method.Call(args)
+	}
+	// The methods that fail return an error as the last result
+	if len(results) > 0 {
+		if err, ok := results[len(results)-1].Interface().(error); ok && err != nil {
+			return err
+		}
+	}
+	return nil
+}
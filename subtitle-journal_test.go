@@ -0,0 +1,187 @@
+package subtitle
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+// The calls that start a journal, with the configuration
+const journalHeader = "SetAlignStrategy|SetSplitOptions|SetRTLOptions|SetMetadata|SetLineSplitter|SetNormalizers"
+
+func TestJournalReplay(t *testing.T) {
+	var journal bytes.Buffer
+	var subt SubtitleSRT
+	subt.SetOriginalSrt(strings.NewReader(alignTestSrt))
+	subt.SetJournal(&journal, "editor")
+	subt.SetAlignStrategy(StatisticalAlignment)
+	subt.SetSplitOptions(SplitOptions{MaxCharsPerLine: 30})
+	subt.SetTranslatedText(alignTestTxt)
+	subt.BeginGroup("Rework")
+	subt.InsertBlock(1, "00:00:03,100 --> 00:00:03,400", "Hello.")
+	subt.MergeBlocks(0)
+	subt.EndGroup()
+	subt.ShiftBlocks(time.Second)
+	subt.MoveWordFromLineToNext(0)
+	subt.Undo()
+	if err := subt.GetJournalError(); err != nil {
+		t.Fatalf("GetJournalError(): %v", err)
+	}
+
+	entries, err := ReadJournal(bytes.NewReader(journal.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadJournal(): %v", err)
+	}
+	ops := make([]string, len(entries))
+	for i, entry := range entries {
+		ops[i] = entry.Op
+		if entry.Author != "editor" || entry.Time.IsZero() {
+			t.Fatalf("ReadJournal(): unexpected entry %+v", entry)
+		}
+	}
+	want := journalHeader + "|SetAlignStrategy|SetSplitOptions|SetTranslatedTextWithOptions|BeginGroup|InsertBlock|MergeBlocks|EndGroup|ShiftBlocks|MoveWordFromLineToNext|Undo"
+	if strings.Join(ops, "|") != want {
+		t.Fatalf("ReadJournal(): want %s have %s", want, strings.Join(ops, "|"))
+	}
+
+	var replayed SubtitleSRT
+	if err := replayed.Replay(strings.NewReader(alignTestSrt), bytes.NewReader(journal.Bytes())); err != nil {
+		t.Fatalf("Replay(): %v", err)
+	}
+	if !replayed.IsEqual(subt) || strings.Join(replayed.GetUndoNames(), "|") != strings.Join(subt.GetUndoNames(), "|") {
+		t.Fatalf("Replay(): unexpected undo %q", replayed.GetUndoNames())
+	}
+	if replayed.GetSplitOptions() != subt.GetSplitOptions() {
+		t.Fatalf("Replay(): unexpected split options %+v", replayed.GetSplitOptions())
+	}
+}
+
+func TestReplayEntryErrors(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, StatisticalAlignment)
+	bad := []string{
+		`{"op":"DeleteSubtitleSrt"}`,
+		`{"op":"DeleteBlock"}`,
+		`{"op":"DeleteBlock","args":["one"]}`,
+		`{"op":"DeleteBlock","args":[99]}`,
+	}
+	for _, line := range bad {
+		entries, err := ReadJournal(strings.NewReader(line))
+		if err != nil {
+			t.Fatalf("ReadJournal(%s): %v", line, err)
+		}
+		if err := subt.ReplayEntry(entries[0]); err == nil {
+			t.Fatalf("ReplayEntry(%s): want error", line)
+		}
+	}
+	if _, err := ReadJournal(strings.NewReader("{\"op\":\"Undo\"}\nnot json\n")); err == nil {
+		t.Fatal("ReadJournal(): want error for a line that is not JSON")
+	}
+}
+
+func TestJournalSkipsFailedEdits(t *testing.T) {
+	var journal bytes.Buffer
+	var subt SubtitleSRT
+	subt.SetOriginalSrt(strings.NewReader(alignTestSrt))
+	subt.SetJournal(&journal, "reviewer")
+	subt.SetAlignStrategy(StatisticalAlignment)
+	subt.SetTranslatedText(alignTestTxt)
+	if err := subt.LockLineSet(0); err != nil {
+		t.Fatalf("LockLineSet(0): %v", err)
+	}
+	if err := subt.SetLineSetStatus(0, StatusApproved); err == nil {
+		t.Fatal("SetLineSetStatus(0): want error for a locked line set")
+	}
+	if err := subt.DeleteBlock(99); err == nil {
+		t.Fatal("DeleteBlock(99): want error")
+	}
+	if err := subt.SetLineSetStatus(1, StatusReviewed); err != nil {
+		t.Fatalf("SetLineSetStatus(1): %v", err)
+	}
+
+	entries, err := ReadJournal(bytes.NewReader(journal.Bytes()))
+	if err != nil {
+		t.Fatalf("ReadJournal(): %v", err)
+	}
+	ops := make([]string, len(entries))
+	for i, entry := range entries {
+		ops[i] = entry.Op
+	}
+	want := journalHeader + "|SetAlignStrategy|SetTranslatedTextWithOptions|LockLineSet|SetLineSetStatus"
+	if strings.Join(ops, "|") != want {
+		t.Fatalf("ReadJournal(): want %s have %s", want, strings.Join(ops, "|"))
+	}
+
+	// The replay clears a SubtitleSRT that is already loaded
+	replayed := newTestSubtitle(alignTestSrt, alignTestTxt, StatisticalAlignment)
+	if err := replayed.Replay(strings.NewReader(alignTestSrt), bytes.NewReader(journal.Bytes())); err != nil {
+		t.Fatalf("Replay(): %v", err)
+	}
+	if len(replayed.subtitleBlock) != len(subt.subtitleBlock) || !replayed.IsEqual(subt) {
+		t.Fatalf("Replay(): want %d blocks have %d", len(subt.subtitleBlock), len(replayed.subtitleBlock))
+	}
+}
+
+func TestReplayConfiguration(t *testing.T) {
+	var journal bytes.Buffer
+	var subt SubtitleSRT
+	subt.SetOriginalSrt(strings.NewReader(alignTestSrt))
+	subt.SetAlignStrategy(StatisticalAlignment)
+	subt.SetRTLOptions(RTLOptions{MovePunctuation: true})
+	subt.SetJournal(&journal, "editor")
+	subt.SetTranslatedText(alignTestTxt)
+
+	// The configuration of the receiver is not the one of the journal
+	var replayed SubtitleSRT
+	replayed.SetSplitOptions(SplitOptions{MaxCharsPerLine: 10})
+	replayed.SetTargetLanguage("ar")
+	if err := replayed.Replay(strings.NewReader(alignTestSrt), bytes.NewReader(journal.Bytes())); err != nil {
+		t.Fatalf("Replay(): %v", err)
+	}
+	if !replayed.IsEqual(subt) {
+		t.Fatal("Replay(): want the SubtitleSRT of the journal")
+	}
+	if replayed.GetSplitOptions() != subt.GetSplitOptions() || replayed.GetRTLOptions() != subt.GetRTLOptions() ||
+		replayed.GetAlignStrategy() != StatisticalAlignment || replayed.GetTargetLanguage() != "" {
+		t.Fatalf("Replay(): unexpected configuration %+v %+v", replayed.GetSplitOptions(), replayed.GetMetadata())
+	}
+}
+
+// upperSplitter is a LineSplitter defined outside the package
+type upperSplitter struct{ ProportionalSplitter }
+
+func TestReplayPlugins(t *testing.T) {
+	var journal bytes.Buffer
+	var subt SubtitleSRT
+	subt.SetOriginalSrt(strings.NewReader(alignTestSrt))
+	subt.SetNormalizers(nil, GetNormalizer("fr"))
+	subt.SetJournal(&journal, "editor")
+	subt.SetLineSplitter(NewOptimalSplitter())
+	subt.SetAlignStrategy(StatisticalAlignment)
+	subt.SetTranslatedText(alignTestTxt)
+
+	// The splitters and the normalizers of the package are created again
+	var replayed SubtitleSRT
+	if err := replayed.Replay(strings.NewReader(alignTestSrt), bytes.NewReader(journal.Bytes())); err != nil {
+		t.Fatalf("Replay(): %v", err)
+	}
+	if !replayed.IsEqual(subt) {
+		t.Fatal("Replay(): want the SubtitleSRT of the journal")
+	}
+	if _, ok := replayed.lineSplitter.(*OptimalSplitter); !ok || replayed.originalNormalizer != nil ||
+		replayed.translatedNormalizer != GetNormalizer("fr") {
+		t.Fatalf("Replay(): unexpected plugins %#v %#v %#v", replayed.lineSplitter, replayed.originalNormalizer, replayed.translatedNormalizer)
+	}
+
+	// The other ones must be in use
+	journal.Reset()
+	subt.SetJournal(&journal, "editor")
+	subt.SetLineSplitter(upperSplitter{})
+	if err := replayed.Replay(strings.NewReader(alignTestSrt), bytes.NewReader(journal.Bytes())); err == nil {
+		t.Fatal("Replay(): want error for a line splitter that is not in use")
+	}
+	replayed.SetLineSplitter(upperSplitter{})
+	if err := replayed.Replay(strings.NewReader(alignTestSrt), bytes.NewReader(journal.Bytes())); err != nil {
+		t.Fatalf("Replay(): %v", err)
+	}
+}
@@ -0,0 +1,369 @@
+package subtitle
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ------------------------------------------------------
+// Quality checks of the subtitles against a style guide
+// ------------------------------------------------------
+
+// The lint profiles shipped with the package, one file per profile
+//
+//go:embed lintprofiles/*.json
+var lintProfilesFiles embed.FS
+
+// Severity is how serious an Issue is
+type Severity int
+
+const (
+	SeverityInfo Severity = iota
+	SeverityWarning
+	SeverityError
+)
+
+var severityNames = []string{"info", "warning", "error"}
+
+// String returns the name of the Severity
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return fmt.Sprintf("severity(%d)", int(s))
+	}
+	return severityNames[s]
+}
+
+// MarshalText writes the Severity by its name
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText reads the Severity by its name
+func (s *Severity) UnmarshalText(text []byte) error {
+	for i, name := range severityNames {
+		if strings.EqualFold(string(text), name) {
+			*s = Severity(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("subtitle: unknown severity %q", text)
+}
+
+// An Issue is a problem found by a LintRule
+// Block and Line are -1 if the issue is not about a block or a line
+type Issue struct {
+	Rule     string
+	Severity Severity
+	Block    int
+	Line     int
+	Message  string
+}
+
+// A LintRule checks the subtitles. The Rule and Severity of the Issues
+// it returns are set from the profile
+type LintRule interface {
+	Check(subt *SubtitleSRT) []Issue
+}
+
+// A LintRuleFactory builds a LintRule from its configuration in a profile
+type LintRuleFactory func(cfg LintRuleConfig) LintRule
+
+// LintRuleConfig is a rule of a LintProfile
+//   - Rule is the name of the rule
+//   - Severity is the severity of its Issues
+//   - Value is the limit of the rule (chars, lines, CPS, milliseconds)
+type LintRuleConfig struct {
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Value    float64  `json:"value,omitempty"`
+}
+
+// LintProfile is a set of rules, as in a client style guide
+type LintProfile struct {
+	Name        string           `json:"name"`
+	Description string           `json:"description,omitempty"`
+	Rules       []LintRuleConfig `json:"rules"`
+}
+
+// The rules available to the profiles
+var lintRules = map[string]LintRuleFactory{
+	"timemark":          builtinLintRule(checkTimemark),
+	"max-cpl":           builtinLintRule(checkMaxCPL),
+	"max-lines":         builtinLintRule(checkMaxLines),
+	"max-cps":           builtinLintRule(checkMaxCPS),
+	"min-duration":      builtinLintRule(checkMinDuration),
+	"max-duration":      builtinLintRule(checkMaxDuration),
+	"min-gap":           builtinLintRule(checkMinGap),
+	"overlap":           builtinLintRule(checkOverlap),
+	"empty-translation": builtinLintRule(checkEmptyTranslation),
+	"trailing-spaces":   builtinLintRule(checkTrailingSpaces),
+	"unbalanced-tags":   builtinLintRule(checkUnbalancedTags),
+}
+
+// RegisterLintRule makes a rule available to the profiles
+// A rule with the name of an existing one replaces it
+func RegisterLintRule(name string, factory LintRuleFactory) {
+	lintRules[name] = factory
+}
+
+// LoadLintProfile reads a LintProfile in JSON format
+func LoadLintProfile(reader io.Reader) (*LintProfile, error) {
+	profile := new(LintProfile)
+	if err := json.NewDecoder(reader).Decode(profile); err != nil {
+		return nil, err
+	}
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// GetLintProfile returns a LintProfile shipped with the package:
+// "streaming" or "broadcast"
+func GetLintProfile(name string) (*LintProfile, error) {
+	file, err := lintProfilesFiles.Open("lintprofiles/" + strings.ToLower(name) + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("subtitle: no lint profile %q", name)
+	}
+	defer file.Close()
+	return LoadLintProfile(file)
+}
+
+// Validate returns an error if a rule of the profile does not exist
+func (this *LintProfile) Validate() error {
+	for _, cfg := range this.Rules {
+		if _, ok := lintRules[cfg.Rule]; !ok {
+			return fmt.Errorf("subtitle: unknown lint rule %q", cfg.Rule)
+		}
+	}
+	return nil
+}
+
+// Lint checks the subtitles with the rules of a profile, and returns
+// the Issues found sorted by block and line
+func (this *SubtitleSRT) Lint(profile *LintProfile) ([]Issue, error) {
+	if profile == nil {
+		return nil, fmt.Errorf("subtitle: no lint profile")
+	}
+	if err := profile.Validate(); err != nil {
+		return nil, err
+	}
+	var issues []Issue
+	for _, cfg := range profile.Rules {
+		for _, issue := range lintRules[cfg.Rule](cfg).Check(this) {
+			issue.Rule = cfg.Rule
+			issue.Severity = cfg.Severity
+			issues = append(issues, issue)
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Block != issues[j].Block {
+			return issues[i].Block < issues[j].Block
+		}
+		return issues[i].Line < issues[j].Line
+	})
+	return issues, nil
+}
+
+// ---------------------------------
+// Built-in rules
+// ---------------------------------
+
+// lintCheck is the check of a built-in rule, value is the limit of the rule
+type lintCheck func(subt *SubtitleSRT, value float64) []Issue
+
+// builtinRule is a LintRule made of a lintCheck and its configuration
+type builtinRule struct {
+	cfg   LintRuleConfig
+	check lintCheck
+}
+
+// Check runs the check of the rule
+func (this builtinRule) Check(subt *SubtitleSRT) []Issue {
+	return this.check(subt, this.cfg.Value)
+}
+
+// builtinLintRule returns the LintRuleFactory of a lintCheck
+func builtinLintRule(check lintCheck) LintRuleFactory {
+	return func(cfg LintRuleConfig) LintRule {
+		return builtinRule{cfg, check}
+	}
+}
+
+// newIssue returns an Issue of a block and line
+func newIssue(block, line int, format string, args ...interface{}) Issue {
+	return Issue{Block: block, Line: line, Message: fmt.Sprintf(format, args...)}
+}
+
+// blockTimes returns the start and end times of all the blocks, and
+// whether the time mark of each block is valid
+func (this *SubtitleSRT) blockTimes() ([]time.Duration, []time.Duration, []bool) {
+	start := make([]time.Duration, len(this.subtitleBlock))
+	end := make([]time.Duration, len(this.subtitleBlock))
+	valid := make([]bool, len(this.subtitleBlock))
+	for b := range this.subtitleBlock {
+		var err error
+		start[b], end[b], err = this.subtitleBlock[b].Times()
+		valid[b] = err == nil
+	}
+	return start, end, valid
+}
+
+func checkTimemark(subt *SubtitleSRT, value float64) []Issue {
+	var issues []Issue
+	for b, sbt := range subt.subtitleBlock {
+		if start, end, err := sbt.Times(); err != nil {
+			issues = append(issues, newIssue(b, -1, "invalid time mark %q", sbt.Timemark))
+		} else if end < start {
+			issues = append(issues, newIssue(b, -1, "ends before it starts"))
+		}
+	}
+	return issues
+}
+
+func checkMaxCPL(subt *SubtitleSRT, value float64) []Issue {
+	var issues []Issue
+	block := subt.blockOfLines()
+	for i, l := range subt.translatedLine {
+		if w := lineWidth(l); float64(w) > value {
+			issues = append(issues, newIssue(block[i], i, "line of %d chars, max %g", w, value))
+		}
+	}
+	return issues
+}
+
+func checkMaxLines(subt *SubtitleSRT, value float64) []Issue {
+	var issues []Issue
+	init := 0
+	for b, sbt := range subt.subtitleBlock {
+		lines := 0
+		for _, l := range subt.translatedLinesOf(init, sbt.Nlines) {
+			if l != "" {
+				lines++
+			}
+		}
+		if float64(lines) > value {
+			issues = append(issues, newIssue(b, -1, "%d lines, max %g", lines, value))
+		}
+		init += sbt.Nlines
+	}
+	return issues
+}
+
+func checkMaxCPS(subt *SubtitleSRT, value float64) []Issue {
+	var issues []Issue
+	for _, s := range subt.CalculateReadingSpeeds() {
+		if s.TranslatedCPS > value {
+			issues = append(issues, newIssue(s.Block, -1, "%.1f chars per second, max %g", s.TranslatedCPS, value))
+		}
+	}
+	return issues
+}
+
+func checkMinDuration(subt *SubtitleSRT, value float64) []Issue {
+	var issues []Issue
+	start, end, valid := subt.blockTimes()
+	for b := range subt.subtitleBlock {
+		if d := end[b] - start[b]; valid[b] && d >= 0 && float64(d.Milliseconds()) < value {
+			issues = append(issues, newIssue(b, -1, "displayed %v, min %gms", d, value))
+		}
+	}
+	return issues
+}
+
+func checkMaxDuration(subt *SubtitleSRT, value float64) []Issue {
+	var issues []Issue
+	start, end, valid := subt.blockTimes()
+	for b := range subt.subtitleBlock {
+		if d := end[b] - start[b]; valid[b] && float64(d.Milliseconds()) > value {
+			issues = append(issues, newIssue(b, -1, "displayed %v, max %gms", d, value))
+		}
+	}
+	return issues
+}
+
+func checkMinGap(subt *SubtitleSRT, value float64) []Issue {
+	var issues []Issue
+	start, end, valid := subt.blockTimes()
+	for b := 1; b < len(subt.subtitleBlock); b++ {
+		gap := start[b] - end[b-1]
+		if valid[b] && valid[b-1] && gap >= 0 && float64(gap.Milliseconds()) < value {
+			issues = append(issues, newIssue(b, -1, "gap of %v with the previous block, min %gms", gap, value))
+		}
+	}
+	return issues
+}
+
+func checkOverlap(subt *SubtitleSRT, value float64) []Issue {
+	var issues []Issue
+	start, end, valid := subt.blockTimes()
+	for b := 1; b < len(subt.subtitleBlock); b++ {
+		if valid[b] && valid[b-1] && start[b] < end[b-1] {
+			issues = append(issues, newIssue(b, -1, "overlaps the previous block by %v", end[b-1]-start[b]))
+		}
+	}
+	return issues
+}
+
+func checkEmptyTranslation(subt *SubtitleSRT, value float64) []Issue {
+	var issues []Issue
+	block := subt.blockOfLines()
+	for i, l := range subt.originalLine {
+		if l != "" && (i >= len(subt.translatedLine) || strings.TrimSpace(subt.translatedLine[i]) == "") {
+			issues = append(issues, newIssue(block[i], i, "line without translation"))
+		}
+	}
+	return issues
+}
+
+func checkTrailingSpaces(subt *SubtitleSRT, value float64) []Issue {
+	var issues []Issue
+	block := subt.blockOfLines()
+	for i, l := range subt.translatedLine {
+		if l != strings.TrimSpace(l) || strings.Contains(l, "  ") {
+			issues = append(issues, newIssue(block[i], i, "leading, trailing or double spaces"))
+		}
+	}
+	return issues
+}
+
+// Formatting tags: <i>, </i>, <font color="...">, and {\an8} style codes
+var (
+	htmlTagRegexp = regexp.MustCompile(`<(/?)([a-zA-Z]+)[^>]*>`)
+)
+
+func checkUnbalancedTags(subt *SubtitleSRT, value float64) []Issue {
+	var issues []Issue
+	init := 0
+	for b, sbt := range subt.subtitleBlock {
+		// Tags may be open in a line and closed in the next one of the block
+		text := strings.Join(subt.translatedLinesOf(init, sbt.Nlines), "\n")
+		init += sbt.Nlines
+
+		var open []string
+		for _, m := range htmlTagRegexp.FindAllStringSubmatch(text, -1) {
+			tag := strings.ToLower(m[2])
+			switch {
+			case m[1] == "":
+				open = append(open, tag)
+			case len(open) > 0 && open[len(open)-1] == tag:
+				open = open[:len(open)-1]
+			default:
+				issues = append(issues, newIssue(b, -1, "closing tag </%s> not open", tag))
+			}
+		}
+		for _, tag := range open {
+			issues = append(issues, newIssue(b, -1, "tag <%s> not closed", tag))
+		}
+		if strings.Count(text, "{") != strings.Count(text, "}") {
+			issues = append(issues, newIssue(b, -1, "unbalanced braces"))
+		}
+	}
+	return issues
+}
@@ -0,0 +1,160 @@
+package subtitle
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// A SRT with timing problems: block 2 is too short and overlaps block 1,
+// block 3 starts right after block 2 and has an invalid time mark
+const lintTestSrt = `1
+00:00:01,000 --> 00:00:03,000
+Good morning, everybody.
+
+2
+00:00:02,900 --> 00:00:03,400
+Hi.
+
+3
+00:00:03,400 -> 00:00:05,000
+Thank you all for coming today.
+`
+
+// lintTestIssues returns the issues as rule:block:line
+func lintTestIssues(issues []Issue) string {
+	var list []string
+	for _, is := range issues {
+		list = append(list, is.Rule+":"+string(rune('0'+is.Block))+":"+string(rune('0'+is.Line+1)))
+	}
+	return strings.Join(list, " ")
+}
+
+func TestLint(t *testing.T) {
+	subt := newTestSubtitle(lintTestSrt, "Buenos días a todos. Hola. Gracias a todos por venir hoy.", StatisticalAlignment)
+	subt.translatedLine = []string{"<i>Buenos días a todos.", "Hola. ", ""}
+
+	profile, err := GetLintProfile("streaming")
+	if err != nil {
+		t.Fatalf("GetLintProfile(): %v", err)
+	}
+	if _, err := subt.Lint(nil); err == nil {
+		t.Fatal("Lint(nil): want error")
+	}
+	issues, err := subt.Lint(profile)
+	if err != nil {
+		t.Fatalf("Lint(): %v", err)
+	}
+	want := "unbalanced-tags:0:0 min-duration:1:0 overlap:1:0 trailing-spaces:1:2 timemark:2:0 empty-translation:2:3"
+	if have := lintTestIssues(issues); have != want {
+		t.Fatalf("Lint(): want %q have %q", want, have)
+	}
+	if issues[0].Severity != SeverityError || issues[3].Severity != SeverityWarning {
+		t.Fatalf("Lint(): unexpected severities %+v", issues)
+	}
+
+	custom, err := LoadLintProfile(strings.NewReader(`{"name": "custom", "rules": [
+		{"rule": "max-cpl", "severity": "info", "value": 20}]}`))
+	if err != nil {
+		t.Fatalf("LoadLintProfile(): %v", err)
+	}
+	issues, _ = subt.Lint(custom)
+	if have := lintTestIssues(issues); have != "max-cpl:0:1" || issues[0].Severity != SeverityInfo {
+		t.Fatalf("Lint(): unexpected %+v", issues)
+	}
+
+	if _, err := LoadLintProfile(strings.NewReader(`{"rules": [{"rule": "no-rule"}]}`)); err == nil {
+		t.Fatalf("LoadLintProfile(): unknown rule not reported")
+	}
+	if _, err := GetLintProfile("none"); err == nil {
+		t.Fatalf("GetLintProfile(): unknown profile not reported")
+	}
+}
+
+// lintRuleSrt returns a SRT with a block per time mark, each with the
+// original lines "Line 1", "Line 2"... as many as lines
+func lintRuleSrt(lines int, timemarks ...string) string {
+	var srt strings.Builder
+	for b, timemark := range timemarks {
+		fmt.Fprintf(&srt, "%d\n%s\n", b+1, timemark)
+		for l := 1; l <= lines; l++ {
+			fmt.Fprintf(&srt, "Line %d\n", l)
+		}
+		srt.WriteString("\n")
+	}
+	return srt.String()
+}
+
+func TestLintRules(t *testing.T) {
+	const (
+		twoSeconds = "00:00:01,000 --> 00:00:03,000"
+		afterGap   = "00:00:03,100 --> 00:00:05,000"
+		afterLong  = "00:00:03,500 --> 00:00:05,000"
+		overlapped = "00:00:02,500 --> 00:00:05,000"
+	)
+	for _, tc := range []struct {
+		rule       string
+		value      float64
+		srt        string
+		translated []string
+		want       int
+	}{
+		{"timemark", 0, lintRuleSrt(1, "00:00:01,000 -> 00:00:03,000"), []string{"Hola"}, 1},
+		{"timemark", 0, lintRuleSrt(1, twoSeconds), []string{"Hola"}, 0},
+		{"max-cpl", 10, lintRuleSrt(1, twoSeconds), []string{"Una línea muy larga"}, 1},
+		{"max-cpl", 10, lintRuleSrt(1, twoSeconds), []string{"Corta"}, 0},
+		{"max-lines", 1, lintRuleSrt(2, twoSeconds), []string{"Hola", "Adiós"}, 1},
+		{"max-lines", 1, lintRuleSrt(2, twoSeconds), []string{"Hola", ""}, 0},
+		{"max-cps", 10, lintRuleSrt(1, twoSeconds), []string{"Una línea con muchos más de veinte caracteres"}, 1},
+		{"max-cps", 10, lintRuleSrt(1, twoSeconds), []string{"Hola"}, 0},
+		{"min-duration", 3000, lintRuleSrt(1, twoSeconds), []string{"Hola"}, 1},
+		{"min-duration", 1000, lintRuleSrt(1, twoSeconds), []string{"Hola"}, 0},
+		{"max-duration", 1000, lintRuleSrt(1, twoSeconds), []string{"Hola"}, 1},
+		{"max-duration", 3000, lintRuleSrt(1, twoSeconds), []string{"Hola"}, 0},
+		{"min-gap", 200, lintRuleSrt(1, twoSeconds, afterGap), []string{"Hola", "Adiós"}, 1},
+		{"min-gap", 200, lintRuleSrt(1, twoSeconds, afterLong), []string{"Hola", "Adiós"}, 0},
+		{"overlap", 0, lintRuleSrt(1, twoSeconds, overlapped), []string{"Hola", "Adiós"}, 1},
+		{"overlap", 0, lintRuleSrt(1, twoSeconds, afterGap), []string{"Hola", "Adiós"}, 0},
+		{"empty-translation", 0, lintRuleSrt(1, twoSeconds), []string{" "}, 1},
+		{"empty-translation", 0, lintRuleSrt(1, twoSeconds), []string{"Hola"}, 0},
+		{"trailing-spaces", 0, lintRuleSrt(1, twoSeconds), []string{"Hola  amigos"}, 1},
+		{"trailing-spaces", 0, lintRuleSrt(1, twoSeconds), []string{"Hola amigos"}, 0},
+		{"unbalanced-tags", 0, lintRuleSrt(2, twoSeconds), []string{"<i>Hola", "amigos"}, 1},
+		{"unbalanced-tags", 0, lintRuleSrt(2, twoSeconds), []string{"<i>Hola", "amigos</i>"}, 0},
+	} {
+		var subt SubtitleSRT
+		subt.SetOriginalSrt(strings.NewReader(tc.srt))
+		subt.translatedLine = tc.translated
+		profile := &LintProfile{Rules: []LintRuleConfig{{Rule: tc.rule, Severity: SeverityWarning, Value: tc.value}}}
+		issues, err := subt.Lint(profile)
+		if err != nil {
+			t.Fatalf("Lint(%s): %v", tc.rule, err)
+		}
+		if len(issues) != tc.want {
+			t.Fatalf("Lint(%s %g, %q): want %d issues have %+v", tc.rule, tc.value, tc.translated, tc.want, issues)
+		}
+	}
+}
+
+func TestLintWithoutTranslation(t *testing.T) {
+	var subt SubtitleSRT
+	subt.SetOriginalSrt(strings.NewReader(lintRuleSrt(2, "00:00:01,000 --> 00:00:03,000")))
+	subt.translatedLine = nil
+	var profile LintProfile
+	for rule := range lintRules {
+		profile.Rules = append(profile.Rules, LintRuleConfig{Rule: rule, Value: 1})
+	}
+	issues, err := subt.Lint(&profile)
+	if err != nil {
+		t.Fatalf("Lint(): %v", err)
+	}
+	if have := lintTestIssues(issues); !strings.Contains(have, "empty-translation:0:1") {
+		t.Fatalf("Lint(): unexpected %q", have)
+	}
+
+	// An unknown rule is reported by Lint too, not only by LoadLintProfile
+	profile.Rules = append(profile.Rules, LintRuleConfig{Rule: "no-rule"})
+	if _, err := subt.Lint(&profile); err == nil {
+		t.Fatal("Lint(): unknown rule not reported")
+	}
+}
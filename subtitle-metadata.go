@@ -0,0 +1,51 @@
+package subtitle
+
+import (
+	"time"
+)
+
+// ------------------------------------------------------
+// Metadata of the project
+// ------------------------------------------------------
+
+// Metadata describes the project of a SubtitleSRT
+//   - SourceLanguage and TargetLanguage are BCP 47 tags, as SetSourceLanguage
+//   - FrameRate is the frames per second of the video, 0 if unknown
+//   - Created is set when the original SRT is imported, Modified by each edit
+//
+// SRT has no header: the other fields are set with SetMetadata. Filling them
+// from the headers of other formats (VTT, ASS, STL) needs their importers
+type Metadata struct {
+	Title          string    `json:"title,omitempty"`
+	Episode        string    `json:"episode,omitempty"`
+	SourceLanguage string    `json:"sourceLanguage,omitempty"`
+	TargetLanguage string    `json:"targetLanguage,omitempty"`
+	FrameRate      float64   `json:"frameRate,omitempty"`
+	Client         string    `json:"client,omitempty"`
+	Translator     string    `json:"translator,omitempty"`
+	Created        time.Time `json:"created"`
+	Modified       time.Time `json:"modified"`
+}
+
+// SetMetadata sets the metadata of the project
+func (this *SubtitleSRT) SetMetadata(m Metadata) {
+	defer this.beginEdit("SetMetadata", m)(nil)
+	this.metadata = m
+}
+
+// GetMetadata returns the metadata of the project
+func (this *SubtitleSRT) GetMetadata() Metadata {
+	return this.metadata
+}
+
+// touchCreated sets the creation time of the project, if it is not set
+func (this *SubtitleSRT) touchCreated() {
+	if this.metadata.Created.IsZero() {
+		this.metadata.Created = time.Now().UTC()
+	}
+}
+
+// touchModified sets the modification time of the project
+func (this *SubtitleSRT) touchModified() {
+	this.metadata.Modified = time.Now().UTC()
+}
@@ -0,0 +1,63 @@
+package subtitle
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetadata(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, ExactMatchAlignment)
+	created := subt.GetMetadata().Created
+	if created.IsZero() || subt.GetMetadata().Modified.Before(created) {
+		t.Fatalf("SetOriginalSrt(): unexpected times %+v", subt.GetMetadata())
+	}
+
+	m := subt.GetMetadata()
+	m.Title, m.Episode, m.FrameRate, m.Translator = "The Show", "S01E02", 25, "Ana"
+	subt.SetMetadata(m)
+	subt.SetSourceLanguage("en")
+	subt.SetTargetLanguage("es")
+	modified := subt.GetMetadata().Modified
+	time.Sleep(time.Millisecond)
+	subt.ShiftBlocks(time.Second)
+	if !subt.GetMetadata().Modified.After(modified) || subt.GetMetadata().Created != created {
+		t.Fatalf("ShiftBlocks(): unexpected times %+v", subt.GetMetadata())
+	}
+
+	// The edits that fail or change nothing keep the modification time
+	modified = subt.GetMetadata().Modified
+	time.Sleep(time.Millisecond)
+	subt.DeleteBlock(99)
+	subt.ShiftBlocks(0)
+	subt.SetTargetLanguage("es")
+	if subt.GetMetadata().Modified != modified {
+		t.Fatalf("DeleteBlock(99): unexpected times %+v", subt.GetMetadata())
+	}
+
+	var buf bytes.Buffer
+	if err := subt.Save(&buf); err != nil {
+		t.Fatalf("Save(): %v", err)
+	}
+	var loaded SubtitleSRT
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatalf("Load(): %v", err)
+	}
+	if have := loaded.GetMetadata(); have.Title != "The Show" || have.SourceLanguage != "en" ||
+		have.TargetLanguage != "es" || have.FrameRate != 25 || !have.Created.Equal(created) {
+		t.Fatalf("Load(): unexpected metadata %+v", have)
+	}
+}
+
+func TestLoadProjectVersion2(t *testing.T) {
+	var subt SubtitleSRT
+	doc := `{"version": 2, "subtitleBlock": [{"Order": "1", "Timemark": "00:00:01,000 --> 00:00:02,000", "Nlines": 1}],
+		"originalLine": ["One."], "translatedLine": [""], "sourceLanguage": "en", "targetLanguage": "ar"}`
+	if err := subt.Load(strings.NewReader(doc)); err != nil {
+		t.Fatalf("Load(): %v", err)
+	}
+	if subt.GetSourceLanguage() != "en" || subt.GetTargetLanguage() != "ar" {
+		t.Fatalf("Load(): languages not migrated %+v", subt.GetMetadata())
+	}
+}
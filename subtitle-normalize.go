@@ -0,0 +1,230 @@
+package subtitle
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ------------------------------------------------
+// Language-aware normalization of the text imported
+// ------------------------------------------------
+
+// A Normalizer cleans up a text before it is stored in SubtitleSRT:
+// the original lines when the SRT is imported, and the translated text
+type Normalizer interface {
+	Normalize(text string) string
+}
+
+// EllipsisStyle is how a TextNormalizer writes an ellipsis
+type EllipsisStyle int
+
+const (
+	// EllipsisAsIs treats the dots of an ellipsis as any other dot
+	EllipsisAsIs EllipsisStyle = iota
+	// EllipsisDots keeps "..." together and writes "…" as "..."
+	EllipsisDots
+	// EllipsisChar writes "..." as "…"
+	EllipsisChar
+)
+
+// TextNormalizer is the configurable Normalizer. Its zero value is the
+// default profile: spaces are collapsed, the space before ,.;:!?)] is
+// removed and a space is added after them
+//   - JoinPunctuation keeps punctuation marks in a row together ("?!")
+//   - FrenchSpacing puts a narrow no-break space before ;!? and a
+//     no-break space before : and » and after «
+//   - PairInvertedMarks removes the space after ¿¡ and adds the opening
+//     mark to the questions and exclamations without it
+//   - ProtectNumbers keeps decimals, thousands and times ("3.5", "10:30")
+//   - ProtectURLs keeps URLs and e-mail addresses
+//   - Ellipsis is how an ellipsis is written
+type TextNormalizer struct {
+	JoinPunctuation   bool
+	FrenchSpacing     bool
+	PairInvertedMarks bool
+	ProtectNumbers    bool
+	ProtectURLs       bool
+	Ellipsis          EllipsisStyle
+}
+
+// Special spaces
+const (
+	noBreakSpace       = "\u00a0"
+	narrowNoBreakSpace = "\u202f"
+)
+
+// Regular expressions of the normalization
+var (
+	spacesRegexp           = regexp.MustCompile(`\s+`)
+	spaceBeforePunctRegexp = regexp.MustCompile(`\s([,:;!\\?\.\)\]])`)
+	spaceAfterPunctRegexp  = regexp.MustCompile(`([,:;!\\?\.\)\]])(\S)`)
+	joinedPunctRegexp      = regexp.MustCompile(`([,:;!\\?\.\)\]])([^\s,:;!?.)\]»"'’”])`)
+
+	frenchSpacesRegexp      = regexp.MustCompile(`[\s\x{00a0}\x{202f}]+`)
+	frenchNarrowRegexp      = regexp.MustCompile(`([^\s\x{00a0}\x{202f};:!?])[\s\x{00a0}\x{202f}]*([;!?]+)`)
+	frenchColonRegexp       = regexp.MustCompile(`([^\s\x{00a0}\x{202f};:!?])[\s\x{00a0}\x{202f}]*(:)`)
+	frenchCloseQuoteRegexp  = regexp.MustCompile(`([^\s\x{00a0}\x{202f}])[\s\x{00a0}\x{202f}]*(»)`)
+	frenchOpenQuoteRegexp   = regexp.MustCompile(`«[\s\x{00a0}\x{202f}]*`)
+	invertedMarkSpaceRegexp = regexp.MustCompile(`([¿¡])\s+`)
+	questionRegexp          = regexp.MustCompile(`[^.!?…]+[!?]+`)
+	sentenceStartRegexp     = regexp.MustCompile(`^[\s"'«“‘(—–-]*`)
+
+	dotsRegexp              = regexp.MustCompile(`\.{3,}`)
+	spaceBeforeEllipsRegexp = regexp.MustCompile(`\s…`)
+	spaceAfterEllipsRegexp  = regexp.MustCompile(`(\S…)([^\s,:;!?.…)\]»"'’”])`)
+
+	numberProtectRegexp = `\d+(?:[.,:]\d+)+`
+	urlProtectRegexp    = `(?i)(?:https?://|www\.)[^\s]*[^\s.,;:!?)\]]|[\w.+-]+@[\w-]+(?:\.[\w-]+)+`
+)
+
+// Normalize returns the text normalized with the profile
+func (n TextNormalizer) Normalize(text string) string {
+	// Protect the pieces that must not be changed
+	text, protected := n.protect(text)
+
+	if n.FrenchSpacing {
+		text = frenchSpacesRegexp.ReplaceAllString(text, " ")
+	} else {
+		text = spacesRegexp.ReplaceAllString(text, " ")
+	}
+	text = spaceBeforePunctRegexp.ReplaceAllString(text, "$1")
+	if n.JoinPunctuation || n.FrenchSpacing {
+		text = joinedPunctRegexp.ReplaceAllString(text, "$1 $2")
+	} else {
+		text = spaceAfterPunctRegexp.ReplaceAllString(text, "$1 $2")
+	}
+	if n.FrenchSpacing {
+		text = frenchNarrowRegexp.ReplaceAllString(text, "$1"+narrowNoBreakSpace+"$2")
+		text = frenchCloseQuoteRegexp.ReplaceAllString(text, "$1"+noBreakSpace+"$2")
+		text = frenchColonRegexp.ReplaceAllString(text, "$1"+noBreakSpace+"$2")
+		text = frenchOpenQuoteRegexp.ReplaceAllString(text, "«"+noBreakSpace)
+	}
+	if n.Ellipsis != EllipsisAsIs {
+		text = n.normalizeEllipsis(text)
+	}
+	if n.PairInvertedMarks {
+		text = pairInvertedMarks(invertedMarkSpaceRegexp.ReplaceAllString(text, "$1"))
+	}
+	text = strings.TrimSpace(text)
+
+	// Restore the protected pieces
+	for i, p := range protected {
+		text = strings.Replace(text, placeholder(i), p, 1)
+	}
+	return text
+}
+
+// protect replaces the numbers and URLs to protect by placeholders,
+// and returns them
+func (n TextNormalizer) protect(text string) (string, []string) {
+	var patterns []string
+	if n.ProtectURLs {
+		patterns = append(patterns, urlProtectRegexp)
+	}
+	if n.ProtectNumbers {
+		patterns = append(patterns, numberProtectRegexp)
+	}
+	if len(patterns) == 0 {
+		return text, nil
+	}
+	var protected []string
+	re := regexp.MustCompile(strings.Join(patterns, "|"))
+	text = re.ReplaceAllStringFunc(text, func(s string) string {
+		protected = append(protected, s)
+		return placeholder(len(protected) - 1)
+	})
+	return text, protected
+}
+
+// normalizeEllipsis writes the ellipses in the style of the profile,
+// without space before them, and with a space after them if they are
+// not at the beginning of a sentence
+func (n TextNormalizer) normalizeEllipsis(text string) string {
+	text = dotsRegexp.ReplaceAllString(text, "…")
+	text = spaceBeforeEllipsRegexp.ReplaceAllString(text, "…")
+	text = spaceAfterEllipsRegexp.ReplaceAllString(text, "$1 $2")
+	if n.Ellipsis == EllipsisDots {
+		text = strings.ReplaceAll(text, "…", "...")
+	}
+	return text
+}
+
+// placeholder returns the placeholder of the i-th protected piece,
+// a char of the Unicode supplementary private use area
+func placeholder(i int) string {
+	return string(rune(0xF0000 + i))
+}
+
+// pairInvertedMarks adds the opening ¿ or ¡ to the questions and
+// exclamations that do not have it
+func pairInvertedMarks(text string) string {
+	out := new(strings.Builder)
+	last := 0
+	for _, loc := range questionRegexp.FindAllStringIndex(text, -1) {
+		sentence := text[loc[0]:loc[1]]
+		opening := "¡"
+		if strings.HasSuffix(strings.TrimRight(sentence, "!"), "?") {
+			opening = "¿"
+		}
+		out.WriteString(text[last:loc[0]])
+		if !strings.Contains(sentence, opening) {
+			start := len(sentenceStartRegexp.FindString(sentence))
+			sentence = sentence[:start] + opening + sentence[start:]
+		}
+		out.WriteString(sentence)
+		last = loc[1]
+	}
+	out.WriteString(text[last:])
+	return out.String()
+}
+
+// GetNormalizer returns the Normalizer profile of a language
+// The language is an ISO 639-1 code, optionally with region ("fr-CA")
+// Languages without a profile get the default one
+func GetNormalizer(lang string) Normalizer {
+	switch baseLanguage(lang) {
+	case "fr":
+		return TextNormalizer{FrenchSpacing: true, ProtectNumbers: true, ProtectURLs: true, Ellipsis: EllipsisChar}
+	case "es":
+		return TextNormalizer{JoinPunctuation: true, PairInvertedMarks: true, ProtectNumbers: true, ProtectURLs: true, Ellipsis: EllipsisDots}
+	case "en", "de", "pt", "it":
+		return TextNormalizer{JoinPunctuation: true, ProtectNumbers: true, ProtectURLs: true, Ellipsis: EllipsisDots}
+	}
+	return TextNormalizer{}
+}
+
+// SetSourceLanguage sets the language of the original text
+func (this *SubtitleSRT) SetSourceLanguage(lang string) {
+	defer this.beginEdit("SetSourceLanguage", lang)(nil)
+	this.metadata.SourceLanguage = lang
+}
+
+// GetSourceLanguage returns the language of the original text
+func (this *SubtitleSRT) GetSourceLanguage() string {
+	return this.metadata.SourceLanguage
+}
+
+// SetNormalizers sets the Normalizers of the original and the translated
+// text, nil selects the profile of the source or target language
+// They take effect the next time a text is imported
+func (this *SubtitleSRT) SetNormalizers(original, translated Normalizer) {
+	this.journalCall("SetNormalizers", newJournalPlugin(original), newJournalPlugin(translated))
+	this.originalNormalizer = original
+	this.translatedNormalizer = translated
+}
+
+// normalizeOriginal normalizes an original text
+func (this *SubtitleSRT) normalizeOriginal(text string) string {
+	if this.originalNormalizer != nil {
+		return this.originalNormalizer.Normalize(text)
+	}
+	return GetNormalizer(this.metadata.SourceLanguage).Normalize(text)
+}
+
+// normalizeTranslated normalizes a translated text
+func (this *SubtitleSRT) normalizeTranslated(text string) string {
+	if this.translatedNormalizer != nil {
+		return this.translatedNormalizer.Normalize(text)
+	}
+	return GetNormalizer(this.metadata.TargetLanguage).Normalize(text)
+}
@@ -0,0 +1,54 @@
+package subtitle
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizerDefault(t *testing.T) {
+	// The default profile is prepareString
+	for _, source := range []string{"   Hi !ya   how r u   ;   ", "Son 3.5 km... a las 10:30"} {
+		if have, want := GetNormalizer("").Normalize(source), prepareString(source); have != want {
+			t.Fatalf("Default Normalizer: want %q have %q", want, have)
+		}
+	}
+	if have := prepareString("Son 3.5 km"); have != "Son 3. 5 km" {
+		t.Fatalf("prepareString(): unexpected %q", have)
+	}
+}
+
+func TestNormalizerProfiles(t *testing.T) {
+	for _, tc := range []struct{ lang, source, want string }{
+		{"en", "It is 3.5 km ,at 10:30 . See www.example.com/a.b , ok ?!", "It is 3.5 km, at 10:30. See www.example.com/a.b, ok?!"},
+		{"en", "Well…I don't know ...", "Well... I don't know..."},
+		{"fr", "Quoi?! « Bonjour » :il est 10:30 ; voilà ...", "Quoi\u202f?! «\u00a0Bonjour\u00a0»\u00a0: il est 10:30\u202f; voilà…"},
+		{"es-MX", "¿ Qué tal? Bien, y tú? Genial!", "¿Qué tal? ¿Bien, y tú? ¡Genial!"},
+	} {
+		if have := GetNormalizer(tc.lang).Normalize(tc.source); have != tc.want {
+			t.Fatalf("Normalizer %q: want %q have %q", tc.lang, tc.want, have)
+		}
+	}
+}
+
+func TestNormalizerOfSubtitle(t *testing.T) {
+	var subt SubtitleSRT
+	subt.SetSourceLanguage("en")
+	subt.SetOriginalSrt(strings.NewReader(alignOptionsTestSrt("It costs 3.50 dollars")))
+	if have := subt.GetOriginalLines()[1]; have != "It costs 3.50 dollars" {
+		t.Fatalf("Original Normalizer: unexpected line %q", have)
+	}
+	subt.SetTargetLanguage("fr")
+	subt.SetTranslatedText("Bonjour ! Ça coûte 3,50 dollars. Au revoir !")
+	if have, _ := subt.GetTranslatedText(); have != "Bonjour\u202f! Ça coûte 3,50 dollars. Au revoir\u202f!" {
+		t.Fatalf("Translated Normalizer: unexpected text %q", have)
+	}
+	// The no-break spaces are not break points
+	for i, l := range subt.GetTranslatedLines() {
+		if strings.HasPrefix(l, "!") {
+			t.Fatalf("Translated Normalizer: Line %d starts with '!': %q", i, l)
+		}
+	}
+	if !subt.IsTranslationConsistent() {
+		t.Fatal("Translated Normalizer: Translation is not consistent")
+	}
+}
@@ -0,0 +1,246 @@
+package subtitle
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// ------------------------------------------------------
+// Order, overlaps and numbering of the subtitle blocks
+// ------------------------------------------------------
+
+// OverlapMode is how NormalizeBlocks resolves the overlapping blocks
+//   - OverlapTrim ends the block when the next one starts, if it does not
+//     start at the same time (then it is flagged)
+//   - OverlapFlag only reports the overlap
+type OverlapMode int
+
+const (
+	OverlapTrim OverlapMode = iota
+	OverlapFlag
+)
+
+// BlockChangeKind is the kind of a change made to the blocks
+//   - BlockMoved: the block moved from the position Old to New
+//   - LineSetRebuilt: the line set Index was rebuilt from the line sets Old
+//     because its lines were not contiguous after sorting the blocks
+//   - BlockTrimmed: the time mark changed from Old to New
+//   - BlockOverlap: the block overlaps the next one, nothing changed
+//   - BlockRenumbered: the order changed from Old to New
+//   - BlockExtended: the time mark changed from Old to New, ending later
+//   - BlockSnapped: the time mark changed from Old to New, to a shot change
+//   - BlockCrossesShot: the block Old crosses the shot change at New
+type BlockChangeKind int
+
+const (
+	BlockMoved BlockChangeKind = iota
+	LineSetRebuilt
+	BlockTrimmed
+	BlockOverlap
+	BlockRenumbered
+	BlockExtended
+	BlockSnapped
+	BlockCrossesShot
+)
+
+// BlockChange is a change made to the blocks, or a problem found in them
+// Index is the block, or the line set for LineSetRebuilt, after the change
+type BlockChange struct {
+	Kind  BlockChangeKind
+	Index int
+	Old   string
+	New   string
+}
+
+// String describes the change
+func (c BlockChange) String() string {
+	switch c.Kind {
+	case BlockMoved:
+		return fmt.Sprintf("block %d: moved from position %s", c.Index, c.Old)
+	case LineSetRebuilt:
+		return fmt.Sprintf("line set %d: rebuilt from line sets %s", c.Index, c.Old)
+	case BlockTrimmed:
+		return fmt.Sprintf("block %d: trimmed from %s to %s", c.Index, c.Old, c.New)
+	case BlockOverlap:
+		return fmt.Sprintf("block %d: %s overlaps the next block", c.Index, c.Old)
+	case BlockRenumbered:
+		return fmt.Sprintf("block %d: renumbered from %q to %q", c.Index, c.Old, c.New)
+	case BlockExtended:
+		return fmt.Sprintf("block %d: extended from %s to %s", c.Index, c.Old, c.New)
+	case BlockSnapped:
+		return fmt.Sprintf("block %d: snapped from %s to %s", c.Index, c.Old, c.New)
+	case BlockCrossesShot:
+		return fmt.Sprintf("block %d: %s crosses the shot change at %s", c.Index, c.Old, c.New)
+	}
+	return fmt.Sprintf("block %d: unknown change", c.Index)
+}
+
+// NormalizeBlocks sorts the blocks by start time, resolves the overlaps and
+// renumbers the blocks from 1, and returns every change made
+// The blocks with an invalid time mark stay after the block they follow
+func (this *SubtitleSRT) NormalizeBlocks(mode OverlapMode) []BlockChange {
+	defer this.beginEdit("NormalizeBlocks", mode)(nil)
+	changes := this.sortBlocks()
+	changes = append(changes, this.resolveOverlaps(mode)...)
+	return append(changes, this.renumberBlocks()...)
+}
+
+// sortBlocks sorts the blocks by start time with their lines, and
+// rebuilds the line sets whose lines are not contiguous any more
+func (this *SubtitleSRT) sortBlocks() []BlockChange {
+	var changes []BlockChange
+
+	// The start time of each block, an invalid one takes the previous one
+	keys := make([]time.Duration, len(this.subtitleBlock))
+	for b := range this.subtitleBlock {
+		start, _, err := this.subtitleBlock[b].Times()
+		if err != nil && b > 0 {
+			start = keys[b-1]
+		}
+		keys[b] = start
+	}
+	order := make([]int, len(this.subtitleBlock))
+	for b := range order {
+		order[b] = b
+	}
+	sort.SliceStable(order, func(i, j int) bool { return keys[order[i]] < keys[order[j]] })
+
+	// newLine is the new position of each line
+	newLine := make([]int, len(this.originalLine))
+	blocks := make([]SubtitleBlock, 0, len(this.subtitleBlock))
+	originalLine := make([]string, 0, len(this.originalLine))
+	translatedLine := make([]string, 0, len(this.translatedLine))
+	moved := false
+	for nb, b := range order {
+		init, last := this.blockLines(b)
+		for i := init; i <= last; i++ {
+			newLine[i] = len(originalLine)
+			originalLine = append(originalLine, this.originalLine[i])
+			translatedLine = append(translatedLine, this.translatedLine[i])
+		}
+		blocks = append(blocks, this.subtitleBlock[b])
+		if nb != b {
+			moved = true
+			changes = append(changes, BlockChange{BlockMoved, nb, strconv.Itoa(b), strconv.Itoa(nb)})
+		}
+	}
+	if !moved {
+		return nil
+	}
+	this.subtitleBlock, this.originalLine, this.translatedLine = blocks, originalLine, translatedLine
+	if !this.IsSplit() {
+		return changes
+	}
+	return append(changes, this.remapLineSets(newLine)...)
+}
+
+// remapLineSets moves the line sets to the new position of their lines
+// The line sets whose lines get mixed are merged, and split again
+// unless one of them is locked: the merged one keeps the lock and the lines
+func (this *SubtitleSRT) remapLineSets(newLine []int) []BlockChange {
+	var changes []BlockChange
+
+	// The range and the order of the lines of each line set in the new positions
+	type remap struct {
+		set      int
+		init     int
+		last     int
+		inOrder  bool
+		nOldSets int
+	}
+	sets := make([]remap, len(this.lineSet))
+	for ls, set := range this.lineSet {
+		sets[ls] = remap{ls, newLine[set.InitLine], newLine[set.InitLine], true, 1}
+		for i := set.InitLine + 1; i <= set.LastLine; i++ {
+			if newLine[i] < newLine[i-1] {
+				sets[ls].inOrder = false
+			}
+			if newLine[i] < sets[ls].init {
+				sets[ls].init = newLine[i]
+			}
+			if newLine[i] > sets[ls].last {
+				sets[ls].last = newLine[i]
+			}
+		}
+	}
+	sort.SliceStable(sets, func(i, j int) bool { return sets[i].init < sets[j].init })
+
+	// Merge the line sets that overlap, the text is joined in the new order
+	var lineSet []LineSet
+	var translatedSet []string
+	var resplit []int
+	for i := 0; i < len(sets); {
+		set := sets[i]
+		old := strconv.Itoa(set.set)
+		merged := this.lineSet[set.set]
+		merged.InitLine = set.init
+		text := this.translatedSet[set.set]
+		for i++; i < len(sets) && sets[i].init <= set.last; i++ {
+			if sets[i].last > set.last {
+				set.last = sets[i].last
+			}
+			set.nOldSets++
+			set.inOrder = set.inOrder && sets[i].inOrder
+			text = joinStrings(text, this.translatedSet[sets[i].set])
+			merged.mergeReview(this.lineSet[sets[i].set])
+			old += "," + strconv.Itoa(sets[i].set)
+		}
+		merged.LastLine = set.last
+		lineSet = append(lineSet, merged)
+		translatedSet = append(translatedSet, text)
+		ls := len(lineSet) - 1
+		if set.nOldSets > 1 || !set.inOrder {
+			lineSet[ls].Exact = false
+			if merged.Locked {
+				// The lines of a locked LineSet are kept, in their new order
+				translatedSet[ls] = joinStrings(this.translatedLine[set.init : set.last+1]...)
+			} else {
+				resplit = append(resplit, ls)
+			}
+			changes = append(changes, BlockChange{LineSetRebuilt, ls, old, strconv.Itoa(ls)})
+		}
+	}
+	this.lineSet, this.translatedSet = lineSet, translatedSet
+
+	for _, ls := range resplit {
+		this.splitTranslatedLineSetIntoLines(ls)
+	}
+	// build the translatedText with the new order of the translatedSet
+	this.translatedText = joinStrings(this.translatedSet...)
+	return changes
+}
+
+// resolveOverlaps trims or flags the blocks that end after the next one starts
+func (this *SubtitleSRT) resolveOverlaps(mode OverlapMode) []BlockChange {
+	var changes []BlockChange
+	for b := 0; b+1 < len(this.subtitleBlock); b++ {
+		start, end, err := this.subtitleBlock[b].Times()
+		next, _, errNext := this.subtitleBlock[b+1].Times()
+		if err != nil || errNext != nil || next >= end {
+			continue
+		}
+		old := this.subtitleBlock[b].Timemark
+		if mode == OverlapTrim && next > start {
+			this.subtitleBlock[b].SetTimes(start, next)
+			changes = append(changes, BlockChange{BlockTrimmed, b, old, this.subtitleBlock[b].Timemark})
+		} else {
+			changes = append(changes, BlockChange{BlockOverlap, b, old, old})
+		}
+	}
+	return changes
+}
+
+// renumberBlocks sets the order of the blocks to 1, 2, 3...
+func (this *SubtitleSRT) renumberBlocks() []BlockChange {
+	var changes []BlockChange
+	for b := range this.subtitleBlock {
+		order := strconv.Itoa(b + 1)
+		if this.subtitleBlock[b].Order != order {
+			changes = append(changes, BlockChange{BlockRenumbered, b, this.subtitleBlock[b].Order, order})
+			this.subtitleBlock[b].Order = order
+		}
+	}
+	return changes
+}
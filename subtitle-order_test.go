@@ -0,0 +1,106 @@
+package subtitle
+
+import (
+	"strings"
+	"testing"
+)
+
+// A SRT with the blocks out of order, block 7 overlaps block 3
+const orderTestSrt = `1
+00:00:01,000 --> 00:00:03,000
+Good morning, everybody.
+
+3
+00:00:06,000 --> 00:00:08,000
+Let's begin.
+
+7
+00:00:03,500 --> 00:00:06,500
+Thank you all
+for coming today.
+`
+
+func TestNormalizeBlocks(t *testing.T) {
+	subt := newTestSubtitle(orderTestSrt, "Buenos días a todos. Empecemos. Gracias a todos por venir hoy.", StatisticalAlignment)
+
+	changes := subt.NormalizeBlocks(OverlapTrim)
+	var report []string
+	for _, c := range changes {
+		report = append(report, c.String())
+	}
+	t.Log(strings.Join(report, "\n"))
+
+	want := []string{"Good morning, everybody.", "Thank you all", "for coming today.", "Let's begin."}
+	if have := subt.GetOriginalLines(); strings.Join(have, "|") != strings.Join(want, "|") {
+		t.Fatalf("NormalizeBlocks(): want lines %q have %q", want, have)
+	}
+	blocks := subt.GetSubtitleBlocks()
+	if blocks[1].Order != "2" || blocks[1].Nlines != 2 || blocks[2].Order != "3" {
+		t.Fatalf("NormalizeBlocks(): unexpected blocks %+v", blocks)
+	}
+	if blocks[1].Timemark != "00:00:03,500 --> 00:00:06,000" {
+		t.Fatalf("NormalizeBlocks(): overlap not trimmed %+v", blocks[1])
+	}
+
+	// The line sets cover all the lines, in order, with all the translation
+	init := 0
+	for _, ls := range subt.GetLineSets() {
+		if ls.InitLine != init || ls.LastLine < ls.InitLine {
+			t.Fatalf("NormalizeBlocks(): unexpected line sets %+v", subt.GetLineSets())
+		}
+		init = ls.LastLine + 1
+	}
+	if init != subt.CountLines() || !subt.IsTranslationConsistent() {
+		t.Fatalf("NormalizeBlocks(): line sets not consistent %+v", subt.GetLineSets())
+	}
+
+	kinds := map[BlockChangeKind]int{}
+	for _, c := range changes {
+		kinds[c.Kind]++
+	}
+	if kinds[BlockMoved] != 2 || kinds[BlockTrimmed] != 1 || kinds[BlockRenumbered] != 1 {
+		t.Fatalf("NormalizeBlocks(): unexpected changes %v", report)
+	}
+
+	// A second pass changes nothing
+	if changes := subt.NormalizeBlocks(OverlapTrim); len(changes) != 0 {
+		t.Fatalf("NormalizeBlocks(): unexpected changes %v", changes)
+	}
+}
+
+func TestNormalizeBlocksFlag(t *testing.T) {
+	var subt SubtitleSRT
+	subt.SetOriginalSrt(strings.NewReader(`1
+00:00:01,000 --> 00:00:03,000
+One.
+
+2
+00:00:01,000 --> 00:00:02,000
+Two.
+`))
+	changes := subt.NormalizeBlocks(OverlapTrim)
+	if len(changes) != 1 || changes[0].Kind != BlockOverlap || changes[0].Index != 0 {
+		t.Fatalf("NormalizeBlocks(): unexpected changes %v", changes)
+	}
+}
+
+func TestNormalizeBlocksKeepsLock(t *testing.T) {
+	subt := newTestSubtitle(orderTestSrt, "Buenos días a todos. Empecemos. Gracias a todos por venir hoy.", StatisticalAlignment)
+	// The LineSet of "Let's begin." and the block 7 gets its lines mixed
+	subt.MergeLineSetWithNext(1)
+	subt.LockLineSet(1)
+	lines := subt.GetTranslatedLines()
+	want := []string{lines[0], lines[2], lines[3], lines[1]}
+
+	subt.NormalizeBlocks(OverlapTrim)
+	sets := subt.GetLineSets()
+	if len(sets) != 2 || !sets[1].Locked || sets[1].InitLine != 1 || sets[1].LastLine != 3 {
+		t.Fatalf("NormalizeBlocks(): unexpected line sets %+v", sets)
+	}
+	if have := subt.GetTranslatedLines(); strings.Join(have, "|") != strings.Join(want, "|") {
+		t.Fatalf("NormalizeBlocks(): want lines %q have %q", want, have)
+	}
+	if !subt.IsTranslationConsistent() {
+		t.Fatal("NormalizeBlocks(): translation not consistent")
+	}
+}
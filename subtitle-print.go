@@ -28,6 +28,19 @@ func (this *SubtitleSRT) PrintLineSetData(f io.Writer) {
 	}
 }
 
+// Print the LineSets sorted by ascending confidence, riskiest first
+func (this *SubtitleSRT) PrintReviewQueue(f io.Writer) {
+	for _, c := range this.GetLineSetsByConfidence() {
+		exact := "inferred"
+		if c.Exact {
+			exact = "exact"
+		}
+		fmt.Fprintf(f, "Lineset %3.3d, Score: %5.3f, %-8s, Lines: %3d, Ratio: %6.4f (%+6.2f%%), Txt: |>%s<|\n",
+			c.LineSet, c.Score, exact, c.Lines, c.Ratio, 100*c.RatioDeviation,
+			PrintStringMaxWidth(this.translatedSet[c.LineSet], 50))
+	}
+}
+
 // Print all the original lines, one per line
 func (this *SubtitleSRT) PrintOriginalLines(f io.Writer) {
 	for i, l := range this.originalLine {
@@ -71,8 +84,8 @@ func (this *SubtitleSRT) PrintLineSetWithSplitData(f io.Writer, theLineSet int)
 	// Iterate over the lines of the lineSet theLineSet
 	for i := this.lineSet[theLineSet].InitLine; i <= this.lineSet[theLineSet].LastLine; i++ {
 
-		lenOrig = len([]rune(this.originalLine[i]))
-		lenTran = len([]rune(this.translatedLine[i]))
+		lenOrig = countChars(this.originalLine[i])
+		lenTran = countChars(this.translatedLine[i])
 		target = ratio*float64(lenOrig) - excess
 		excess = float64(lenTran) - target
 
@@ -111,6 +124,7 @@ func (this *SubtitleSRT) PrintOriginalSRT(f io.Writer) {
 }
 
 // Print the SRT file, with the translated lines
+// If the target language is right-to-left, the RTLOptions are applied
 func (this *SubtitleSRT) PrintTranslatedSRT(f io.Writer) {
 
 	// Keep count of the lines
@@ -119,7 +133,7 @@ func (this *SubtitleSRT) PrintTranslatedSRT(f io.Writer) {
 	for _, sbt := range this.subtitleBlock {
 		sbt.Print(f)
 		for i := 0; i < sbt.Nlines; i++ {
-			fmt.Fprintln(f, this.translatedLine[n])
+			fmt.Fprintln(f, this.translatedLineForOutput(this.translatedLine[n]))
 			n++
 		}
 		fmt.Fprintln(f)
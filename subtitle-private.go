@@ -1,7 +1,7 @@
 package subtitle
 
 import (
-	"fmt"
+	"math"
 	"regexp"
 	"strings"
 )
@@ -23,7 +23,7 @@ func (this *SubtitleSRT) appendSubtitle(data string) {
 		theLine := ""
 		// If there is a line, get it
 		if len(lines) > nLine {
-			theLine = prepareString(lines[nLine])
+			theLine = this.normalizeOriginal(lines[nLine])
 		}
 		// If it is not an empty line, or it is the first one, add it
 		if theLine != "" || nLine == 2 {
@@ -47,6 +47,7 @@ func (this *SubtitleSRT) splitTranslatedTextIntoLineSets() {
 	//         2.1. If the line is empty, it matches it with [] in the translated text
 	//         2.2. If the line is between [.*], it matches anything [.*]
 	//         2.3. If the line is longer than minmatch, it matches the same exact text
+	//         (the token, the bracket pattern and minmatch are set with AlignOptions)
 	//              (so that we avoid matching things like 'a' or 'is' in the middle of a line)
 	//   3. Make blocks of contiguous lines that match exactly (isExact line sets) or not.
 	//      Each block stores the init and last line (both inclusive)
@@ -54,15 +55,23 @@ func (this *SubtitleSRT) splitTranslatedTextIntoLineSets() {
 	//      Worth to mention, the [] representing blank lines in the translated text are not
 	//      stored in the translated set.
 
-	// the min length of a line to be matched in the translation
-	const minmatch = 15
+	// the min length of a line to be matched in the translation,
+	// the regexps for lines between brackets and the empty line token
+	opts := this.alignOptions
+	minmatch := opts.minMatch()
+	bracketLine, bracketGroup := opts.bracketRegexps()
+	emptyLine := regexp.QuoteMeta(opts.emptyLineToken())
+	caseFlag := ""
+	if opts.IgnoreCase {
+		caseFlag = "(?i)"
+	}
 
 	// Kind: isExact or not - initialy not
 	currentSetIsExact := false
 	// Translated text to be splitted
 	data := this.translatedText
 	// Create the first newLineSet to store first/last line and newTranslatedSet to store text
-	newLineSet := LineSet{0, 0}
+	newLineSet := LineSet{InitLine: 0, LastLine: 0}
 	newTranslatedSet := ""
 	// String for the searchRegexp
 	searchRegexp := ""
@@ -76,16 +85,16 @@ func (this *SubtitleSRT) splitTranslatedTextIntoLineSets() {
 
 		// Set the searchRegExp
 		if theLine == "" {
-			// This line is empty, search for []
-			searchRegexp = `\[\]`
-		} else if matched, _ := regexp.MatchString(`\[.+\]`, theLine); matched {
+			// This line is empty, search for [] (the empty line token)
+			searchRegexp = emptyLine
+		} else if bracketLine.MatchString(theLine) {
 			// This line is a word or several between brackets
-			searchRegexp = `\[([^]]+)\]`
+			searchRegexp = bracketGroup.String()
 		} else {
 			// This line is a text line
-			searchRegexp = regexp.QuoteMeta(theLine)
-			// Verify if it isMiniLine
-			isMiniLine = len([]rune(theLine)) < minmatch
+			searchRegexp = caseFlag + regexp.QuoteMeta(theLine)
+			// Verify if it isMiniLine (unless mini lines may match mid-text)
+			isMiniLine = !opts.MiniLinesMidText && countChars(theLine) < minmatch
 		}
 
 		// Can the searchRegexp be found in the translation?
@@ -101,10 +110,11 @@ func (this *SubtitleSRT) splitTranslatedTextIntoLineSets() {
 			// The line was not found
 			if currentSetIsExact {
 				// If current line set isExact, append the new line set
+				newLineSet.Exact = true
 				this.lineSet = append(this.lineSet, newLineSet)
 				this.translatedSet = append(this.translatedSet, newTranslatedSet)
 				// and open a new lineset that !isExact
-				newLineSet = LineSet{i, i}
+				newLineSet = LineSet{InitLine: i, LastLine: i}
 				newTranslatedSet = ""
 				currentSetIsExact = false
 			} else {
@@ -118,7 +128,7 @@ func (this *SubtitleSRT) splitTranslatedTextIntoLineSets() {
 				// newLineSet only can be isExact, or it is the initial one
 				// Add the line to the newLineSet and the text to the translated line
 				newLineSet.LastLine = i
-				newTranslatedSet = ConcatWithSpace(newTranslatedSet, data[:loc[1]])
+				newTranslatedSet = opts.concatWithSpace(newTranslatedSet, data[:loc[1]])
 				// This line set continues isExact (just in case we are in the initial line)
 				currentSetIsExact = true
 				// Retire the found string
@@ -133,17 +143,23 @@ func (this *SubtitleSRT) splitTranslatedTextIntoLineSets() {
 				this.lineSet = append(this.lineSet, newLineSet)
 				this.translatedSet = append(this.translatedSet, strings.TrimSpace(newTranslatedSet))
 				// Open a newLineSet that isExact
-				newLineSet = LineSet{i, i}
-				newTranslatedSet = ConcatWithSpace("", data[loc[0]:loc[1]])
+				newLineSet = LineSet{InitLine: i, LastLine: i}
+				newTranslatedSet = opts.concatWithSpace("", data[loc[0]:loc[1]])
 				currentSetIsExact = true
-				data = data[loc[1]+1:]
+				data = strings.TrimSpace(data[loc[1]:])
 			}
 		}
 	}
 
-	// If there is something left in data, it is the last block
-	if data != "" {
+	// The last line set is still open: what is left in data has not been
+	// matched, and goes to it. It is only isExact if nothing is left
+	if currentSetIsExact {
+		newLineSet.Exact = strings.TrimSpace(data) == ""
+		newTranslatedSet = opts.concatWithSpace(newTranslatedSet, strings.TrimSpace(data))
+	} else {
 		newTranslatedSet = strings.TrimSpace(data)
+	}
+	if len(this.originalLine) > 0 {
 		this.lineSet = append(this.lineSet, newLineSet)
 		this.translatedSet = append(this.translatedSet, newTranslatedSet)
 	}
@@ -151,68 +167,32 @@ func (this *SubtitleSRT) splitTranslatedTextIntoLineSets() {
 
 // Split the text assigned to a LineSet into lines
 func (this *SubtitleSRT) splitTranslatedLineSetIntoLines(theLineSet int) {
+	// The lines of a locked LineSet are kept
+	if this.lineSet[theLineSet].Locked {
+		return
+	}
 
 	// Calculate the ratio translated:original for this line set
 	ratio := this.CalculateRatioOfLineSet(theLineSet)
 
-	// Initialize the values
-	data := this.translatedSet[theLineSet]
-	excess := 0.0
-
-	// Iterate over the lines of the lineSet theLineSet
+	// The target size of each translated line is proportional to the original one
+	// Note that target is float64!
 	init := this.lineSet[theLineSet].InitLine
 	last := this.lineSet[theLineSet].LastLine
-	for i := init; i <= last; i++ {
-		// Set target size for the translated line
-		// Note that target is float64!
-		lenOrig := len([]rune(this.originalLine[i]))
-		target := ratio*float64(lenOrig) - excess
-		newLine := ""
-
-		if i == last {
-			// If this is the last line, output the rest of the data
-			newLine = data
-			excess = float64(len([]rune(newLine))) - target + 1.0
-		} else if lenOrig == 0 {
-			// If the original line is empty, output empty data and keep excess
-			newLine = ""
-		} else if data == "" {
-			// if the remaining string is "", return an empty string
-			newLine = ""
-			excess = -target
-			// (****) and maybe raise a warning here!!
-		} else {
-			var chars int
-			var subStrMax, subStrMin, re string
-			// Find the next "split point" after "target" characters, defined as
-			// <chars>{target-1} + <no-sep>{+} + <sep>{+}
-			if target > 1 {
-				chars = int(target + 0.5)
-			}
-			// Get the substring until the next separator after {chars}
-			// Note: It must be {chars} unicode points not ascii (.)
-			// re = fmt.Sprintf(`^([\P{M}\p{M}]{%d}[^\s]*[\s]*)`, chars)
-			re = fmt.Sprintf(`^([\P{M}\p{M}]{%d}[^\s]*)`, chars)
-			subStrMax = regexp.MustCompile(re).FindString(data)
-			if subStrMax == "" {
-				subStrMax = data
-			}
-			// Get the substring until the prev separator before .{chars}
-			// re = fmt.Sprintf(`(\\p{Z}*[^\s]*[\s]*)$`)
-			re = fmt.Sprintf(`\p{Z}*[^\s]*$`)
-			subStrMin = strings.TrimSuffix(subStrMax, regexp.MustCompile(re).FindString(subStrMax))
-
-			// Now, let's apply euristic rules to define what to return...
-			// ...
-			// If subStrMin=="", output is subStrMax (so, min one word)
-			// in other case, return the closest to target ([]runes)
-			newLine, excess = ClosestNotEmptyString(target, subStrMin, subStrMax)
-			// Food for thoughts:
-			//   - prioritize if the subStr ends with a punct
+	targets := make([]float64, last-init+1)
+	for i := range targets {
+		lenOrig := countChars(this.originalLine[init+i])
+		targets[i] = ratio * float64(lenOrig)
+		if lenOrig != 0 && targets[i] == 0 {
+			// Keep it apart from the empty original lines
+			targets[i] = math.SmallestNonzeroFloat64
 		}
-		// Now, update the output and data
-		// this.translatedLine[i] = strings.TrimSpace(newLine)
-		this.translatedLine[i] = newLine
-		data = strings.TrimSpace(strings.TrimPrefix(data, newLine))
 	}
+
+	// Split the text and update the output
+	lines := this.getLineSplitter().SplitLines(this.translatedSet[theLineSet], targets)
+	copy(this.translatedLine[init:last+1], lines)
+
+	// Reflow the lines that do not honour the SplitOptions
+	this.fitLineSetToConstraints(theLineSet)
 }
@@ -0,0 +1,204 @@
+package subtitle
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// ------------------------------------------------------
+// Project document: the SubtitleSRT in a single versioned JSON document
+// ------------------------------------------------------
+
+// ProjectVersion is the schema version of the documents written by Save
+//   - 1 is the layout of WriteToFile, the six files in a single object
+//   - 2 adds the options and the languages, with a version field
+//   - 3 moves the languages into the metadata
+const ProjectVersion = 3
+
+// projectDocument is the current schema of the project document
+type projectDocument struct {
+	Version        int             `json:"version"`
+	SubtitleBlock  []SubtitleBlock `json:"subtitleBlock"`
+	LineSet        []LineSet       `json:"lineSet"`
+	OriginalLine   []string        `json:"originalLine"`
+	TranslatedLine []string        `json:"translatedLine"`
+	TranslatedSet  []string        `json:"translatedSet"`
+	TranslatedText string          `json:"translatedText"`
+	AlignStrategy  AlignStrategy   `json:"alignStrategy"`
+	AlignOptions   AlignOptions    `json:"alignOptions"`
+	SplitOptions   SplitOptions    `json:"splitOptions"`
+	RTLOptions     RTLOptions      `json:"rtlOptions"`
+	Metadata       Metadata        `json:"metadata"`
+}
+
+// A projectMigration converts a document of a version into the next one
+type projectMigration func(doc map[string]json.RawMessage) error
+
+// The migrations of the documents, projectMigrations[v] converts v into v+1
+var projectMigrations = map[int]projectMigration{
+	1: migrateProjectV1,
+	2: migrateProjectV2,
+}
+
+// migrateProjectV1 renames the fields named after the files of WriteToFile
+func migrateProjectV1(doc map[string]json.RawMessage) error {
+	names := map[string]string{
+		"subtitleblock":  "subtitleBlock",
+		"lineset":        "lineSet",
+		"originalline":   "originalLine",
+		"translatedline": "translatedLine",
+		"translatedset":  "translatedSet",
+		"translatedtext": "translatedText",
+	}
+	for from, to := range names {
+		if value, ok := doc[from]; ok {
+			doc[to] = value
+			delete(doc, from)
+		}
+	}
+	return nil
+}
+
+// migrateProjectV2 moves the languages into the metadata
+func migrateProjectV2(doc map[string]json.RawMessage) error {
+	var metadata Metadata
+	for name, lang := range map[string]*string{
+		"sourceLanguage": &metadata.SourceLanguage,
+		"targetLanguage": &metadata.TargetLanguage,
+	} {
+		if value, ok := doc[name]; ok {
+			if err := json.Unmarshal(value, lang); err != nil {
+				return err
+			}
+			delete(doc, name)
+		}
+	}
+	data, err := json.Marshal(metadata)
+	doc["metadata"] = data
+	return err
+}
+
+// MarshalJSON writes the blocks, lines, LineSet:s, options and metadata
+// of the SubtitleSRT into a document of the current version
+// The line splitter, the normalizers and the history are not written
+func (this *SubtitleSRT) MarshalJSON() ([]byte, error) {
+	return json.Marshal(projectDocument{
+		Version:        ProjectVersion,
+		SubtitleBlock:  this.subtitleBlock,
+		LineSet:        this.lineSet,
+		OriginalLine:   this.originalLine,
+		TranslatedLine: this.translatedLine,
+		TranslatedSet:  this.translatedSet,
+		TranslatedText: this.translatedText,
+		AlignStrategy:  this.alignStrategy,
+		AlignOptions:   this.alignOptions,
+		SplitOptions:   this.splitOptions,
+		RTLOptions:     this.rtlOptions,
+		Metadata:       this.metadata,
+	})
+}
+
+// UnmarshalJSON reads a document of any version, migrating it to the
+// current one. A document without version is of version 1
+// The line splitter and the normalizers are kept, the history is cleared
+func (this *SubtitleSRT) UnmarshalJSON(data []byte) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	version := 1
+	if raw, ok := fields["version"]; ok {
+		if err := json.Unmarshal(raw, &version); err != nil {
+			return fmt.Errorf("subtitle: invalid project version %s", raw)
+		}
+	}
+	if version < 1 || version > ProjectVersion {
+		return fmt.Errorf("subtitle: unsupported project version %d", version)
+	}
+	for ; version < ProjectVersion; version++ {
+		if err := projectMigrations[version](fields); err != nil {
+			return fmt.Errorf("subtitle: migrating project version %d: %v", version, err)
+		}
+	}
+	fields["version"] = json.RawMessage(fmt.Sprint(version))
+
+	migrated, err := json.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	var doc projectDocument
+	if err := json.Unmarshal(migrated, &doc); err != nil {
+		return err
+	}
+	if err := doc.validate(); err != nil {
+		return err
+	}
+
+	this.subtitleBlock = doc.SubtitleBlock
+	this.lineSet = doc.LineSet
+	this.originalLine = doc.OriginalLine
+	this.translatedLine = doc.TranslatedLine
+	this.translatedSet = doc.TranslatedSet
+	this.translatedText = doc.TranslatedText
+	this.alignStrategy = doc.AlignStrategy
+	this.alignOptions = doc.AlignOptions
+	this.splitOptions = doc.SplitOptions
+	this.rtlOptions = doc.RTLOptions
+	this.metadata = doc.Metadata
+	this.ClearHistory()
+	return nil
+}
+
+// validate returns an error if the lines do not match the blocks
+// or the LineSet:s do not cover the lines, one after the other
+// A document without translated lines gets empty ones
+func (doc *projectDocument) validate() error {
+	lines := 0
+	for _, block := range doc.SubtitleBlock {
+		lines += block.Nlines
+	}
+	if lines != len(doc.OriginalLine) {
+		return fmt.Errorf("subtitle: project has %d original lines, the blocks %d", len(doc.OriginalLine), lines)
+	}
+	if len(doc.TranslatedLine) == 0 && lines > 0 {
+		doc.TranslatedLine = make([]string, lines)
+	}
+	if len(doc.TranslatedLine) != lines {
+		return fmt.Errorf("subtitle: project has %d translated lines, the blocks %d", len(doc.TranslatedLine), lines)
+	}
+	if len(doc.TranslatedSet) != len(doc.LineSet) {
+		return fmt.Errorf("subtitle: project has %d translated sets, the line sets %d", len(doc.TranslatedSet), len(doc.LineSet))
+	}
+	next := 0
+	for i, ls := range doc.LineSet {
+		if ls.InitLine != next || ls.LastLine < ls.InitLine || ls.LastLine >= lines {
+			return fmt.Errorf("subtitle: project line set %d does not follow the previous one", i)
+		}
+		next = ls.LastLine + 1
+	}
+	if len(doc.LineSet) > 0 && next != lines {
+		return fmt.Errorf("subtitle: project line sets end at line %d, the blocks have %d", next, lines)
+	}
+	return nil
+}
+
+// Save writes the SubtitleSRT into a single JSON document
+func (this *SubtitleSRT) Save(writer io.Writer) error {
+	data, err := json.MarshalIndent(this, "", " ")
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(append(data, '\n'))
+	return err
+}
+
+// Load reads the SubtitleSRT from a JSON document written by Save,
+// or by a previous version
+func (this *SubtitleSRT) Load(reader io.Reader) error {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, this)
+}
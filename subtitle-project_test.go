@@ -0,0 +1,111 @@
+package subtitle
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveLoad(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, StatisticalAlignment)
+	subt.SetSplitOptions(SplitOptions{MaxCharsPerLine: 30})
+	subt.SetTargetLanguage("he")
+	subt.SetRTLOptions(RTLOptions{Mark: RLMMark, MovePunctuation: true})
+
+	var buf bytes.Buffer
+	if err := subt.Save(&buf); err != nil {
+		t.Fatalf("Save(): %v", err)
+	}
+	if !strings.Contains(buf.String(), `"version": 3`) {
+		t.Fatalf("Save(): no version in %s", buf.String())
+	}
+	var loaded SubtitleSRT
+	if err := loaded.Load(&buf); err != nil {
+		t.Fatalf("Load(): %v", err)
+	}
+	if !loaded.IsEqual(subt) || !loaded.IsTranslationConsistent() {
+		t.Fatal("Load(): loaded project is different from the saved one")
+	}
+	if loaded.GetAlignStrategy() != StatisticalAlignment || loaded.GetSplitOptions() != subt.GetSplitOptions() ||
+		loaded.GetTargetLanguage() != "he" || loaded.GetRTLOptions() != subt.GetRTLOptions() {
+		t.Fatal("Load(): options not loaded")
+	}
+}
+
+func TestLoadLegacyFiles(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, ExactMatchAlignment)
+	dir := t.TempDir()
+	fields := map[string]interface{}{
+		"subtitleblock":  subt.subtitleBlock,
+		"lineset":        subt.lineSet,
+		"originalline":   subt.originalLine,
+		"translatedline": subt.translatedLine,
+		"translatedset":  subt.translatedSet,
+		"translatedtext": subt.translatedText,
+	}
+	for name, value := range fields {
+		data, _ := json.MarshalIndent(value, "", " ")
+		if err := ioutil.WriteFile(filepath.Join(dir, name+".json"), data, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var loaded SubtitleSRT
+	if err := loaded.LoadLegacyFiles(dir); err != nil {
+		t.Fatalf("LoadLegacyFiles(): %v", err)
+	}
+	if !loaded.IsEqual(subt) {
+		t.Fatal("LoadLegacyFiles(): loaded project is different from the original")
+	}
+	if err := loaded.LoadLegacyFiles(t.TempDir()); err == nil {
+		t.Fatal("LoadLegacyFiles(): want error for a directory without files")
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	bad := []string{
+		`{"version": 4}`,
+		`{"version": "two"}`,
+		`{"version": 2, "subtitleBlock": [{"Order": "1", "Timemark": "", "Nlines": 2}], "originalLine": ["One"]}`,
+		`{"version": 2, "subtitleBlock": [{"Order": "1", "Timemark": "", "Nlines": 1}], "originalLine": ["One"],
+			"lineSet": [{"InitLine": 0, "LastLine": 1}]}`,
+		// A translated set missing
+		`{"version": 3, "subtitleBlock": [{"Order": "1", "Timemark": "", "Nlines": 1}], "originalLine": ["Hi"],
+			"lineSet": [{"InitLine": 0, "LastLine": 0}], "translatedSet": []}`,
+		// Translated lines that are not the lines of the blocks
+		`{"version": 3, "subtitleBlock": [{"Order": "1", "Timemark": "", "Nlines": 1}], "originalLine": ["Hi"],
+			"translatedLine": ["Hola", "Adiós"]}`,
+		// Line sets with a gap, overlapped, or not reaching the last line
+		`{"version": 3, "subtitleBlock": [{"Order": "1", "Timemark": "", "Nlines": 3}], "originalLine": ["A", "B", "C"],
+			"lineSet": [{"InitLine": 0, "LastLine": 0}, {"InitLine": 2, "LastLine": 2}], "translatedSet": ["A", "C"]}`,
+		`{"version": 3, "subtitleBlock": [{"Order": "1", "Timemark": "", "Nlines": 3}], "originalLine": ["A", "B", "C"],
+			"lineSet": [{"InitLine": 0, "LastLine": 1}, {"InitLine": 1, "LastLine": 2}], "translatedSet": ["A", "C"]}`,
+		`{"version": 3, "subtitleBlock": [{"Order": "1", "Timemark": "", "Nlines": 3}], "originalLine": ["A", "B", "C"],
+			"lineSet": [{"InitLine": 0, "LastLine": 1}], "translatedSet": ["A"]}`,
+		`{"version": 3, "subtitleBlock": [{"Order": "1", "Timemark": "", "Nlines": 3}], "originalLine": ["A", "B", "C"],
+			"lineSet": [{"InitLine": 1, "LastLine": 2}], "translatedSet": ["A"]}`,
+		`[]`,
+	}
+	for _, doc := range bad {
+		var subt SubtitleSRT
+		if err := subt.Load(strings.NewReader(doc)); err == nil {
+			t.Fatalf("Load(%s): want error", doc)
+		}
+	}
+}
+
+func TestLoadWithoutTranslatedLines(t *testing.T) {
+	doc := `{"version": 3, "subtitleBlock": [{"Order": "1", "Timemark": "", "Nlines": 2}], "originalLine": ["Hi", "there"],
+		"lineSet": [{"InitLine": 0, "LastLine": 1}], "translatedSet": ["Hola"]}`
+	var subt SubtitleSRT
+	if err := subt.Load(strings.NewReader(doc)); err != nil {
+		t.Fatalf("Load(): %v", err)
+	}
+	if len(subt.translatedLine) != 2 || subt.translatedLine[0] != "" || subt.translatedLine[1] != "" {
+		t.Fatalf("Load(): want 2 empty translated lines, have %q", subt.translatedLine)
+	}
+	subt.CalculateReadingSpeeds()
+}
@@ -0,0 +1,166 @@
+package subtitle
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ------------------------------------------------------
+// Find and replace in the translation of the LineSet:s
+// ------------------------------------------------------
+
+// FindOptions configures FindReplace
+//   - Regexp: the pattern is a regular expression, and the replacement
+//     can use its groups ($1, ${name}); otherwise both are literal
+//   - WholeWord: the match is not part of a longer word
+//   - IgnoreCase: the pattern matches regardless of case
+//   - LineSets: only these LineSet:s (nil means all of them)
+//   - From, To: only the LineSet:s with a block displayed between them (To 0 means no end)
+//   - DryRun: the matches are returned, nothing is replaced
+type FindOptions struct {
+	Regexp     bool
+	WholeWord  bool
+	IgnoreCase bool
+	LineSets   []int
+	From       time.Duration
+	To         time.Duration
+	DryRun     bool
+}
+
+// A Match is a match of FindReplace in the translation of a LineSet
+//   - Start and End are the byte offsets of Text in the translation of the LineSet
+//   - Replacement is the text that replaces it
+//   - Locked is true if the LineSet is locked, and the match is not replaced
+type Match struct {
+	LineSet     int
+	Start       int
+	End         int
+	Text        string
+	Replacement string
+	Locked      bool
+}
+
+// compile returns the regexp of a pattern with the options
+func (o FindOptions) compile(pattern string) (*regexp.Regexp, error) {
+	if !o.Regexp {
+		pattern = regexp.QuoteMeta(pattern)
+	}
+	if o.IgnoreCase {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("subtitle: invalid pattern: %v", err)
+	}
+	return re, nil
+}
+
+// FindReplace replaces a pattern in the translation of the LineSet:s,
+// and splits again the changed ones into lines. It returns the matches
+// in order, with those of the locked LineSet:s that are not replaced
+func (this *SubtitleSRT) FindReplace(pattern, replacement string, opts FindOptions) (matches []Match, err error) {
+	if !opts.DryRun {
+		defer this.beginEdit("FindReplace", pattern, replacement, opts)(&err)
+	}
+	if pattern == "" {
+		return nil, fmt.Errorf("subtitle: empty pattern")
+	}
+	re, err := opts.compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	sets, err := this.findScope(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ls := range sets {
+		text := this.translatedSet[ls]
+		var found []Match
+		for _, sub := range re.FindAllStringSubmatchIndex(text, -1) {
+			if sub[0] == sub[1] || (opts.WholeWord && !isWholeWord(text, sub[0], sub[1])) {
+				continue
+			}
+			m := Match{ls, sub[0], sub[1], text[sub[0]:sub[1]], replacement, this.lineSet[ls].Locked}
+			if opts.Regexp {
+				m.Replacement = string(re.ExpandString(nil, replacement, text, sub))
+			}
+			found = append(found, m)
+		}
+		matches = append(matches, found...)
+		if opts.DryRun || len(found) == 0 || this.lineSet[ls].Locked {
+			continue
+		}
+
+		// Replace the matches, from the last one so that the offsets are valid
+		for i := len(found) - 1; i >= 0; i-- {
+			text = text[:found[i].Start] + found[i].Replacement + text[found[i].End:]
+		}
+		this.translatedSet[ls] = this.normalizeTranslated(text)
+		this.splitTranslatedLineSetIntoLines(ls)
+	}
+	if !opts.DryRun {
+		// build the translatedText with the new translatedSet
+		this.translatedText = joinStrings(this.translatedSet...)
+	}
+	return matches, nil
+}
+
+// findScope returns the LineSet:s to search, in order
+func (this *SubtitleSRT) findScope(opts FindOptions) ([]int, error) {
+	selected := make([]bool, len(this.lineSet))
+	if opts.LineSets == nil {
+		for ls := range selected {
+			selected[ls] = true
+		}
+	}
+	for _, ls := range opts.LineSets {
+		if err := this.checkLineSet(ls); err != nil {
+			return nil, err
+		}
+		selected[ls] = true
+	}
+
+	var sets []int
+	if opts.From == 0 && opts.To == 0 {
+		for ls, ok := range selected {
+			if ok {
+				sets = append(sets, ls)
+			}
+		}
+		return sets, nil
+	}
+	// A LineSet is in the time range if one of its blocks is
+	start, end, valid := this.blockTimes()
+	block := this.blockOfLines()
+	for ls, ok := range selected {
+		if !ok {
+			continue
+		}
+		for b := block[this.lineSet[ls].InitLine]; b <= block[this.lineSet[ls].LastLine]; b++ {
+			if valid[b] && end[b] > opts.From && (opts.To == 0 || start[b] < opts.To) {
+				sets = append(sets, ls)
+				break
+			}
+		}
+	}
+	return sets, nil
+}
+
+// isWholeWord returns true if the text from start to end is not
+// preceded or followed by a letter or a digit
+func isWholeWord(text string, start, end int) bool {
+	isWordRune := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+	}
+	if before, _ := utf8.DecodeLastRuneInString(text[:start]); start > 0 && isWordRune(before) {
+		return false
+	}
+	if after, _ := utf8.DecodeRuneInString(text[end:]); end < len(text) && isWordRune(after) {
+		return false
+	}
+	return true
+}
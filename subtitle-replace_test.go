@@ -0,0 +1,67 @@
+package subtitle
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFindReplace(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, StatisticalAlignment)
+	subt.ClearHistory()
+	before := *subt.snapshot()
+
+	// "todos" is a word in the LineSet:s 0 and 1, not in "todoso"
+	matches, err := subt.FindReplace("TODOS", "vosotros", FindOptions{WholeWord: true, IgnoreCase: true, DryRun: true})
+	if err != nil || len(matches) != 2 || matches[0].LineSet != 0 || matches[1].LineSet != 1 || matches[0].Text != "todos" {
+		t.Fatalf("FindReplace(): unexpected %+v, %v", matches, err)
+	}
+	if !subt.IsEqual(before) {
+		t.Fatal("FindReplace(): DryRun changed the translation")
+	}
+
+	matches, err = subt.FindReplace(`(\w+) mil años`, "${1} siglos", FindOptions{Regexp: true})
+	if err != nil || len(matches) != 1 || matches[0].Replacement != "dos siglos" {
+		t.Fatalf("FindReplace(): unexpected %+v, %v", matches, err)
+	}
+	if !subt.IsTranslationConsistent() || !strings.Contains(joinStrings(subt.GetTranslatedLines()...), "hace dos siglos por") {
+		t.Fatalf("FindReplace(): unexpected lines %q", subt.GetTranslatedLines())
+	}
+	if names := subt.GetUndoNames(); len(names) != 1 || !strings.HasPrefix(names[0], "FindReplace(") {
+		t.Fatalf("FindReplace(): unexpected undo %q", names)
+	}
+
+	if _, err := subt.FindReplace("(", "", FindOptions{Regexp: true}); err == nil {
+		t.Fatal("FindReplace(): want error for an invalid regexp")
+	}
+	if _, err := subt.FindReplace("a", "", FindOptions{LineSets: []int{9}}); err == nil {
+		t.Fatal("FindReplace(): want error for an invalid line set")
+	}
+}
+
+func TestFindReplaceScope(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, StatisticalAlignment)
+	subt.LockLineSet(1)
+
+	// The LineSet 1 is locked
+	matches, _ := subt.FindReplace("todos", "ustedes", FindOptions{})
+	if len(matches) != 2 || matches[0].Locked || !matches[1].Locked {
+		t.Fatalf("FindReplace(): unexpected %+v", matches)
+	}
+	if text, _ := subt.GetTranslatedTextOfLineSet(1); !strings.Contains(text, "todos") {
+		t.Fatalf("FindReplace(): locked line set changed to %q", text)
+	}
+
+	// Only the LineSet:s of the blocks displayed after 3.5s
+	if matches, _ := subt.FindReplace("gracias", "", FindOptions{IgnoreCase: true, From: 3500 * time.Millisecond, DryRun: true}); len(matches) != 0 {
+		t.Fatalf("FindReplace(): unexpected %+v", matches)
+	}
+	if matches, _ := subt.FindReplace("pequeño", "", FindOptions{From: 8 * time.Second, To: 9 * time.Second, DryRun: true}); len(matches) != 1 {
+		t.Fatalf("FindReplace(): unexpected %+v", matches)
+	}
+
+	matches, _ = subt.FindReplace("a", "A", FindOptions{LineSets: []int{0}, WholeWord: true})
+	if text, _ := subt.GetTranslatedTextOfLineSet(0); len(matches) != 1 || text != "Buenos días A ustedes." {
+		t.Fatalf("FindReplace(): unexpected %q, %+v", text, matches)
+	}
+}
@@ -0,0 +1,159 @@
+package subtitle
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// ------------------------------------------------------
+// Distribution of the translation by the time of the blocks
+// ------------------------------------------------------
+
+// ResegmentLineSetByDuration splits the translated text of a LineSet into
+// lines in proportion to the time of their blocks, instead of the chars
+// of the original lines. Within a block, the text is split by the chars of
+// the original lines, and the empty original lines stay empty
+func (this *SubtitleSRT) ResegmentLineSetByDuration(theLineSet int) (err error) {
+	defer this.beginEdit("ResegmentLineSetByDuration", theLineSet)(&err)
+	if theLineSet < 0 || theLineSet >= len(this.lineSet) {
+		return fmt.Errorf("subtitle: invalid line set %d", theLineSet)
+	}
+	if this.lineSet[theLineSet].Locked {
+		return fmt.Errorf("subtitle: line set %d is locked", theLineSet)
+	}
+	init := this.lineSet[theLineSet].InitLine
+	last := this.lineSet[theLineSet].LastLine
+	block := this.blockOfLines()
+
+	// The weight of each line is its share of the time of its block
+	weights := make([]float64, last-init+1)
+	total := 0.0
+	for i := init; i <= last; {
+		b := block[i]
+		j := i
+		blockChars := 0
+		for ; j <= last && block[j] == b; j++ {
+			blockChars += countChars(this.originalLine[j])
+		}
+		duration := this.subtitleBlock[b].Duration()
+		if duration <= 0 {
+			return fmt.Errorf("subtitle: block %d has no valid duration", b)
+		}
+		for k := i; k < j && blockChars > 0; k++ {
+			weights[k-init] = duration.Seconds() * float64(countChars(this.originalLine[k])) / float64(blockChars)
+			total += weights[k-init]
+		}
+		i = j
+	}
+	if total == 0 {
+		return nil
+	}
+
+	chars := float64(this.CountTranslatedCharsInLineSet(theLineSet))
+	targets := make([]float64, len(weights))
+	for i, w := range weights {
+		targets[i] = chars * w / total
+		if w != 0 && targets[i] == 0 {
+			// Keep it apart from the empty original lines
+			targets[i] = math.SmallestNonzeroFloat64
+		}
+	}
+
+	// Split the text and update the output, as splitTranslatedLineSetIntoLines
+	lines := this.getLineSplitter().SplitLines(this.translatedSet[theLineSet], targets)
+	copy(this.translatedLine[init:last+1], lines)
+	this.fitLineSetToConstraints(theLineSet)
+	return nil
+}
+
+// BoundaryProposal is a move of the boundary between a block and the next
+// one: the block ends at To instead of From, and the gap is kept
+//   - CPS is the highest chars per second of the translation of both blocks after the move
+//   - MeetsTarget is true if CPS is not over the target
+type BoundaryProposal struct {
+	Block       int
+	From        time.Duration
+	To          time.Duration
+	CPS         float64
+	MeetsTarget bool
+}
+
+// The shortest time a block is displayed after a BoundaryProposal,
+// 5/6 of a second (20 frames at 24 fps)
+const minProposedDuration = 5 * time.Second / 6
+
+// ProposeBlockBoundaries proposes the moves of the boundaries between
+// blocks that balance the reading speed of the translation, for the pairs
+// of blocks where one is over maxCPS. The proposals are made from the first
+// block to the last, each one with the times of the previous proposals
+// Each block is displayed at least minProposedDuration, even without text
+func (this *SubtitleSRT) ProposeBlockBoundaries(maxCPS float64) []BoundaryProposal {
+	var proposals []BoundaryProposal
+	n := len(this.subtitleBlock)
+	start, end, valid := this.blockTimes()
+	chars := make([]int, n)
+	for i, b := range this.blockOfLines() {
+		chars[b] += countChars(this.translatedLine[i])
+	}
+
+	cps := func(b int) float64 {
+		if end[b] <= start[b] {
+			return math.Inf(1)
+		}
+		return float64(chars[b]) / (end[b] - start[b]).Seconds()
+	}
+	for b := 0; b+1 < n; b++ {
+		gap := start[b+1] - end[b]
+		if !valid[b] || !valid[b+1] || gap < 0 || chars[b]+chars[b+1] == 0 {
+			continue
+		}
+		before := math.Max(cps(b), cps(b+1))
+		if before <= maxCPS {
+			continue
+		}
+		// Both blocks have the same speed when the time is split by their chars
+		available := end[b+1] - gap - start[b]
+		if available < 2*minProposedDuration {
+			continue
+		}
+		to := start[b] + time.Duration(float64(available)*float64(chars[b])/float64(chars[b]+chars[b+1]))
+		if to < start[b]+minProposedDuration {
+			to = start[b] + minProposedDuration
+		} else if to > end[b+1]-gap-minProposedDuration {
+			to = end[b+1] - gap - minProposedDuration
+		}
+		oldEnd, oldStart := end[b], start[b+1]
+		end[b], start[b+1] = to, to+gap
+		after := math.Max(cps(b), cps(b+1))
+		if after >= before || to == oldEnd {
+			end[b], start[b+1] = oldEnd, oldStart
+			continue
+		}
+		proposals = append(proposals, BoundaryProposal{b, oldEnd, to, after, after <= maxCPS})
+	}
+	return proposals
+}
+
+// ApplyBoundaryProposal moves the boundary between a block and the next one
+func (this *SubtitleSRT) ApplyBoundaryProposal(p BoundaryProposal) (err error) {
+	defer this.beginEdit("ApplyBoundaryProposal", p)(&err)
+	if p.Block < 0 || p.Block+1 >= len(this.subtitleBlock) {
+		return fmt.Errorf("subtitle: invalid block %d", p.Block)
+	}
+	start, end, err := this.subtitleBlock[p.Block].Times()
+	if err != nil {
+		return err
+	}
+	next, nextEnd, err := this.subtitleBlock[p.Block+1].Times()
+	if err != nil {
+		return err
+	}
+	gap := next - end
+	if p.To <= start || p.To+gap >= nextEnd {
+		return fmt.Errorf("subtitle: boundary %v out of blocks %d and %d", p.To, p.Block, p.Block+1)
+	}
+	this.subtitleBlock[p.Block].SetTimes(start, p.To)
+	this.subtitleBlock[p.Block+1].SetTimes(p.To+gap, nextEnd)
+	return nil
+}
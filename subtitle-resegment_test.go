@@ -0,0 +1,85 @@
+package subtitle
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResegmentLineSetByDuration(t *testing.T) {
+	// All the text before [Music] is the LineSet 0..3, of blocks 0 and 1
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, ExactMatchAlignment)
+	subt.subtitleBlock[0].SetTimes(time.Second, 2*time.Second)
+	subt.subtitleBlock[1].SetTimes(2*time.Second, 6*time.Second)
+
+	if err := subt.ResegmentLineSetByDuration(0); err != nil {
+		t.Fatalf("ResegmentLineSetByDuration(): %v", err)
+	}
+	if !subt.IsTranslationConsistent() {
+		t.Fatalf("ResegmentLineSetByDuration(): translation not consistent %q", subt.GetTranslatedLines())
+	}
+	// The block 1 is displayed 4 times longer than the block 0
+	lines := subt.GetTranslatedLines()
+	first, second := countChars(joinStrings(lines[0:2]...)), countChars(joinStrings(lines[2:4]...))
+	if second < 3*first {
+		t.Fatalf("ResegmentLineSetByDuration(): unexpected lines %q", lines)
+	}
+	if err := subt.ResegmentLineSetByDuration(9); err == nil {
+		t.Fatal("ResegmentLineSetByDuration(): want error for an invalid line set")
+	}
+}
+
+func TestProposeBlockBoundaries(t *testing.T) {
+	var subt SubtitleSRT
+	subt.SetOriginalSrt(strings.NewReader(`1
+00:00:01,000 --> 00:00:02,000
+One.
+
+2
+00:00:02,200 --> 00:00:06,200
+Two.
+`))
+	// 30 chars in 1 second and 20 chars in 4 seconds
+	subt.translatedLine = []string{strings.Repeat("a", 30), strings.Repeat("b", 20)}
+
+	proposals := subt.ProposeBlockBoundaries(20)
+	want := BoundaryProposal{0, 2 * time.Second, 4 * time.Second, 10, true}
+	if len(proposals) != 1 || proposals[0] != want {
+		t.Fatalf("ProposeBlockBoundaries(): want %+v have %+v", want, proposals)
+	}
+	if err := subt.ApplyBoundaryProposal(proposals[0]); err != nil {
+		t.Fatalf("ApplyBoundaryProposal(): %v", err)
+	}
+	if have := timemarksOf(&subt); have != "00:00:01,000 --> 00:00:04,000|00:00:04,200 --> 00:00:06,200" {
+		t.Fatalf("ApplyBoundaryProposal(): unexpected %s", have)
+	}
+	if proposals := subt.ProposeBlockBoundaries(20); len(proposals) != 0 {
+		t.Fatalf("ProposeBlockBoundaries(): unexpected %+v", proposals)
+	}
+}
+
+func TestProposeBlockBoundariesWithoutText(t *testing.T) {
+	var subt SubtitleSRT
+	subt.SetOriginalSrt(strings.NewReader(`1
+00:00:01,000 --> 00:00:02,000
+One.
+
+2
+00:00:02,200 --> 00:00:06,200
+Two.
+`))
+	// The second block has no translation, and keeps the shortest duration
+	subt.translatedLine = []string{strings.Repeat("a", 60), ""}
+
+	proposals := subt.ProposeBlockBoundaries(20)
+	to := 6*time.Second - minProposedDuration
+	if len(proposals) != 1 || proposals[0].To != to || !proposals[0].MeetsTarget {
+		t.Fatalf("ProposeBlockBoundaries(): want boundary at %v have %+v", to, proposals)
+	}
+	if err := subt.ApplyBoundaryProposal(proposals[0]); err != nil {
+		t.Fatalf("ApplyBoundaryProposal(): %v", err)
+	}
+	if have := timemarksOf(&subt); have != "00:00:01,000 --> 00:00:05,167|00:00:05,367 --> 00:00:06,200" {
+		t.Fatalf("ApplyBoundaryProposal(): unexpected %s", have)
+	}
+}
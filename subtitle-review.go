@@ -0,0 +1,247 @@
+package subtitle
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ------------------------------------------------------
+// Review of the LineSet:s: status, comments and locks
+// ------------------------------------------------------
+
+// LineSetStatus is the step of the review a LineSet is in
+type LineSetStatus int
+
+const (
+	StatusDraft LineSetStatus = iota
+	StatusTranslated
+	StatusReviewed
+	StatusApproved
+)
+
+// The names of the LineSetStatus, as written in JSON
+var statusNames = []string{"draft", "translated", "reviewed", "approved"}
+
+// String returns the name of the LineSetStatus
+func (s LineSetStatus) String() string {
+	if s < 0 || int(s) >= len(statusNames) {
+		return fmt.Sprintf("status(%d)", int(s))
+	}
+	return statusNames[s]
+}
+
+// MarshalText writes the LineSetStatus by its name
+func (s LineSetStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText reads the LineSetStatus by its name
+func (s *LineSetStatus) UnmarshalText(text []byte) error {
+	for i, name := range statusNames {
+		if strings.EqualFold(string(text), name) {
+			*s = LineSetStatus(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("subtitle: unknown status %q", text)
+}
+
+// A Comment is a note of a reviewer on a LineSet
+type Comment struct {
+	Author string
+	Time   time.Time
+	Text   string
+}
+
+// ReviewProgress counts the LineSet:s by status
+type ReviewProgress struct {
+	Total    int
+	Locked   int
+	ByStatus map[LineSetStatus]int
+}
+
+// IsEqual returns true if the two LineSet:s are the same
+func (this LineSet) IsEqual(other LineSet) bool {
+	if this.InitLine != other.InitLine || this.LastLine != other.LastLine || this.Exact != other.Exact ||
+		this.Status != other.Status || this.Locked != other.Locked || len(this.Comments) != len(other.Comments) {
+		return false
+	}
+	for i, c := range this.Comments {
+		if c.Author != other.Comments[i].Author || !c.Time.Equal(other.Comments[i].Time) || c.Text != other.Comments[i].Text {
+			return false
+		}
+	}
+	return true
+}
+
+// mergeReview merges the review of two LineSet:s into the first one
+// The status is the lower one, the comments are kept in order
+func (this *LineSet) mergeReview(other LineSet) {
+	if other.Status < this.Status {
+		this.Status = other.Status
+	}
+	this.Locked = this.Locked || other.Locked
+	if len(other.Comments) > 0 {
+		this.Comments = append(append([]Comment(nil), this.Comments...), other.Comments...)
+	}
+}
+
+// checkLineSet returns an error if the LineSet does not exist
+func (this *SubtitleSRT) checkLineSet(ls int) error {
+	if ls < 0 || ls >= len(this.lineSet) {
+		return fmt.Errorf("subtitle: invalid line set %d", ls)
+	}
+	return nil
+}
+
+// IsLineSetLocked returns true if the LineSet exists and is locked
+func (this *SubtitleSRT) IsLineSetLocked(ls int) bool {
+	return ls >= 0 && ls < len(this.lineSet) && this.lineSet[ls].Locked
+}
+
+// hasLockedLineSets returns true if any LineSet is locked
+func (this *SubtitleSRT) hasLockedLineSets() bool {
+	for _, ls := range this.lineSet {
+		if ls.Locked {
+			return true
+		}
+	}
+	return false
+}
+
+// SetLineSetStatus sets the review status of a LineSet
+// The status of a locked LineSet cannot change
+func (this *SubtitleSRT) SetLineSetStatus(ls int, status LineSetStatus) (err error) {
+	defer this.beginEdit("SetLineSetStatus", ls, status)(&err)
+	if err := this.checkLineSet(ls); err != nil {
+		return err
+	}
+	if status < StatusDraft || status > StatusApproved {
+		return fmt.Errorf("subtitle: invalid status %v", status)
+	}
+	if this.lineSet[ls].Locked {
+		return fmt.Errorf("subtitle: line set %d is locked", ls)
+	}
+	this.lineSet[ls].Status = status
+	return nil
+}
+
+// LockLineSet locks a LineSet: its lines and its translation are kept
+// by the actions, the re-splitting and SetTranslatedText
+func (this *SubtitleSRT) LockLineSet(ls int) (err error) {
+	defer this.beginEdit("LockLineSet", ls)(&err)
+	if err := this.checkLineSet(ls); err != nil {
+		return err
+	}
+	this.lineSet[ls].Locked = true
+	return nil
+}
+
+// UnlockLineSet unlocks a LineSet
+func (this *SubtitleSRT) UnlockLineSet(ls int) (err error) {
+	defer this.beginEdit("UnlockLineSet", ls)(&err)
+	if err := this.checkLineSet(ls); err != nil {
+		return err
+	}
+	this.lineSet[ls].Locked = false
+	return nil
+}
+
+// AddLineSetComment adds a comment to a LineSet, locked or not
+// The time of the comment is set by the caller
+func (this *SubtitleSRT) AddLineSetComment(ls int, c Comment) (err error) {
+	defer this.beginEdit("AddLineSetComment", ls, c)(&err)
+	if err := this.checkLineSet(ls); err != nil {
+		return err
+	}
+	comments := this.lineSet[ls].Comments
+	this.lineSet[ls].Comments = append(append([]Comment(nil), comments...), c)
+	return nil
+}
+
+// GetReviewProgress counts the LineSet:s by status, and the locked ones
+func (this *SubtitleSRT) GetReviewProgress() ReviewProgress {
+	progress := ReviewProgress{Total: len(this.lineSet), ByStatus: make(map[LineSetStatus]int)}
+	for _, ls := range this.lineSet {
+		progress.ByStatus[ls.Status]++
+		if ls.Locked {
+			progress.Locked++
+		}
+	}
+	return progress
+}
+
+// detectLineSetsAroundLocked splits the translated text into LineSets,
+// keeping the locked ones: the translation of each locked LineSet is found
+// in the text, and the text between them is aligned with the lines between them
+// Nothing changes if the translation of a locked LineSet is not in the text
+func (this *SubtitleSRT) detectLineSetsAroundLocked(text string) error {
+	var lineSet []LineSet
+	var translatedSet []string
+	align := func(init, last int, txt string) error {
+		txt = strings.TrimSpace(txt)
+		if init > last {
+			if txt != "" {
+				return fmt.Errorf("subtitle: translation %q is between locked line sets", txt)
+			}
+			return nil
+		}
+		part := SubtitleSRT{
+			originalLine:   this.originalLine[init : last+1],
+			translatedText: txt,
+			alignStrategy:  this.alignStrategy,
+			alignOptions:   this.alignOptions,
+		}
+		part.detectLineSets()
+		if len(part.lineSet) == 0 {
+			part.lineSet = []LineSet{{InitLine: 0, LastLine: last - init}}
+			part.translatedSet = []string{txt}
+		}
+		for i, ls := range part.lineSet {
+			ls.InitLine += init
+			ls.LastLine += init
+			lineSet = append(lineSet, ls)
+			translatedSet = append(translatedSet, part.translatedSet[i])
+		}
+		return nil
+	}
+
+	cursor, line := 0, 0
+	for i, ls := range this.lineSet {
+		if !ls.Locked {
+			continue
+		}
+		loc := this.lockedTextRegexp(this.translatedSet[i]).FindStringIndex(text[cursor:])
+		if loc == nil {
+			return fmt.Errorf("subtitle: translation of the locked line set %d is not in the text", i)
+		}
+		if err := align(line, ls.InitLine-1, text[cursor:cursor+loc[0]]); err != nil {
+			return err
+		}
+		lineSet = append(lineSet, ls)
+		translatedSet = append(translatedSet, this.translatedSet[i])
+		cursor += loc[1]
+		line = ls.LastLine + 1
+	}
+	if err := align(line, len(this.originalLine)-1, text[cursor:]); err != nil {
+		return err
+	}
+
+	this.translatedText = text
+	this.lineSet = lineSet
+	this.translatedSet = translatedSet
+	return nil
+}
+
+// lockedTextRegexp matches the translation of a LineSet in the text,
+// with any spaces or empty line tokens between its words
+func (this *SubtitleSRT) lockedTextRegexp(text string) *regexp.Regexp {
+	words := strings.Fields(text)
+	for i, w := range words {
+		words[i] = regexp.QuoteMeta(w)
+	}
+	separator := `(?:\s|` + regexp.QuoteMeta(this.alignOptions.emptyLineToken()) + `)+`
+	return regexp.MustCompile(strings.Join(words, separator))
+}
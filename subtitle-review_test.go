@@ -0,0 +1,119 @@
+package subtitle
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLineSetReview(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, StatisticalAlignment)
+	subt.SetLineSetStatus(0, StatusReviewed)
+	subt.SetLineSetStatus(1, StatusApproved)
+	note := Comment{"Ana", time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC), "Too long"}
+	subt.AddLineSetComment(1, note)
+	subt.LockLineSet(2)
+	if err := subt.SetLineSetStatus(2, StatusApproved); err == nil {
+		t.Fatal("SetLineSetStatus(): want error for a locked line set")
+	}
+	if err := subt.SetLineSetStatus(9, StatusApproved); err == nil {
+		t.Fatal("SetLineSetStatus(): want error for an invalid line set")
+	}
+
+	progress := subt.GetReviewProgress()
+	if progress.Total != 6 || progress.Locked != 1 || progress.ByStatus[StatusDraft] != 4 ||
+		progress.ByStatus[StatusReviewed] != 1 || progress.ByStatus[StatusApproved] != 1 {
+		t.Fatalf("GetReviewProgress(): unexpected %+v", progress)
+	}
+
+	// The status and the comments are saved
+	var buf bytes.Buffer
+	subt.Save(&buf)
+	if !strings.Contains(buf.String(), `"Status": "approved"`) {
+		t.Fatalf("Save(): status not written by name in %s", buf.String())
+	}
+	var loaded SubtitleSRT
+	if err := loaded.Load(&buf); err != nil || !loaded.IsEqual(subt) {
+		t.Fatalf("Load(): review not loaded, %v", err)
+	}
+
+	// The merged LineSet has the lower status and the comments of both
+	subt.MergeLineSetWithNext(0)
+	if ls := subt.GetLineSets()[0]; ls.Status != StatusReviewed || len(ls.Comments) != 1 || ls.Comments[0] != note {
+		t.Fatalf("MergeLineSetWithNext(): unexpected %+v", ls)
+	}
+	subt.Undo()
+	if ls := subt.GetLineSets()[1]; ls.Status != StatusApproved {
+		t.Fatalf("Undo(): unexpected %+v", ls)
+	}
+}
+
+func TestLockedLineSetActions(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, StatisticalAlignment)
+	subt.LockLineSet(2)
+	before := *subt.snapshot()
+
+	subt.MoveWordsFromLineSetToPrev(2, 1)
+	subt.MoveWordsFromLineSetToNext(1, 1)
+	subt.MoveLinesFromLineSetToNext(2, 1)
+	subt.MoveWordFromLineToNext(2)
+	subt.SplitLineSetByLine(2, 3)
+	subt.MergeLineSetWithPrev(2)
+	subt.MergeLineSetWithNext(2)
+	subt.SetTranslatedTextOfLineSet(2, "Otra cosa.")
+	if err := subt.ResegmentLineSetByDuration(2); err == nil {
+		t.Fatal("ResegmentLineSetByDuration(): want error for a locked line set")
+	}
+	if !subt.IsEqual(before) {
+		t.Fatal("Actions: a locked line set was changed")
+	}
+
+	subt.UnlockLineSet(2)
+	subt.MergeLineSetWithPrev(2)
+	if len(subt.GetLineSets()) != 5 {
+		t.Fatal("MergeLineSetWithPrev(): unlocked line set not merged")
+	}
+}
+
+func TestSetTranslatedTextKeepsLockedLineSets(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, StatisticalAlignment)
+	subt.LockLineSet(2)
+	subt.LockLineSet(4)
+	locked := []LineSet{subt.GetLineSets()[2], subt.GetLineSets()[4]}
+	lines := subt.GetTranslatedLines()
+	kept := []string{lines[2], lines[3], lines[6], lines[7]}
+
+	txt := `Hola a todos. Gracias por estar aquí. ` +
+		`Vamos a hablar de la historia de la ciudad. [Música] [] ` +
+		`Fue fundada hace dos mil años por un pequeño grupo de pescadores. Comencemos ya.`
+	if err := subt.SetTranslatedTextWithOptions(txt, AlignOptions{}); err != nil {
+		t.Fatalf("SetTranslatedTextWithOptions(): %v", err)
+	}
+	if !subt.IsTranslationConsistent() {
+		t.Fatalf("SetTranslatedTextWithOptions(): translation not consistent %q", subt.GetTranslatedLines())
+	}
+	sets := subt.GetLineSets()
+	if ls := sets[2]; !ls.IsEqual(locked[0]) {
+		t.Fatalf("SetTranslatedTextWithOptions(): locked line set changed to %+v", ls)
+	}
+	if ls := sets[len(sets)-2]; !ls.IsEqual(locked[1]) {
+		t.Fatalf("SetTranslatedTextWithOptions(): locked line set changed to %+v", ls)
+	}
+	lines = subt.GetTranslatedLines()
+	if have := []string{lines[2], lines[3], lines[6], lines[7]}; strings.Join(have, "|") != strings.Join(kept, "|") {
+		t.Fatalf("SetTranslatedTextWithOptions(): want %q have %q", kept, have)
+	}
+	if text := joinStrings(lines...); !strings.HasPrefix(text, "Hola a todos.") || !strings.HasSuffix(text, "Comencemos ya.") {
+		t.Fatalf("SetTranslatedTextWithOptions(): text not aligned again %q", lines)
+	}
+
+	// Without the translation of a locked LineSet, nothing changes
+	before := *subt.snapshot()
+	if err := subt.SetTranslatedTextWithOptions("Otra traducción.", AlignOptions{}); err == nil || !subt.IsEqual(before) {
+		t.Fatalf("SetTranslatedTextWithOptions(): want error and no change, have %v", err)
+	}
+	if subt.SetTranslatedText("Otra traducción."); !subt.IsEqual(before) {
+		t.Fatal("SetTranslatedText(): want no change")
+	}
+}
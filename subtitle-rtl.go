@@ -0,0 +1,142 @@
+package subtitle
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ----------------------------------------------------
+// Right-to-left languages in the translated SRT output
+// ----------------------------------------------------
+
+// DirectionalMark is the Unicode control added to each translated line
+// of a right-to-left language so that players display it in RTL
+type DirectionalMark int
+
+const (
+	// NoMark adds nothing to the lines
+	NoMark DirectionalMark = iota
+	// RLMMark starts each line with a RIGHT-TO-LEFT MARK (U+200F)
+	RLMMark
+	// RLEMark embeds each line between RIGHT-TO-LEFT EMBEDDING (U+202B)
+	// and POP DIRECTIONAL FORMATTING (U+202C)
+	RLEMark
+)
+
+// Unicode directional controls
+const (
+	rlm = "\u200f"
+	lrm = "\u200e"
+	rle = "\u202b"
+	pdf = "\u202c"
+)
+
+// RTLOptions is the handling of the translated lines of a right-to-left
+// language when the SRT is printed
+//   - Mark is the directional mark added to each line
+//   - MovePunctuation moves the punctuation at the end of a line to its
+//     beginning, and the one at the beginning (dialogue dashes, ¿¡) to its
+//     end, for players that display the lines left-to-right
+//   - IsolateNumbers surrounds numbers with LEFT-TO-RIGHT MARKs so that
+//     their digits and separators keep their order
+//
+// The lines are kept unchanged in SubtitleSRT, only the output is affected.
+type RTLOptions struct {
+	Mark            DirectionalMark
+	MovePunctuation bool
+	IsolateNumbers  bool
+}
+
+// Languages and scripts written right-to-left
+var (
+	rtlLanguages = map[string]bool{
+		"ar": true, "arc": true, "ckb": true, "dv": true, "fa": true, "he": true,
+		"iw": true, "ps": true, "sd": true, "ug": true, "ur": true, "yi": true,
+	}
+	rtlScripts = map[string]bool{
+		"adlm": true, "arab": true, "hebr": true, "nkoo": true,
+		"rohg": true, "syrc": true, "thaa": true,
+	}
+)
+
+// Regular expressions to place punctuation and numbers in RTL lines
+var (
+	rtlLeadingPunctRegexp  = regexp.MustCompile(`^([-–—¿¡.,;:!?…،؛؟]+)(\s*)`)
+	rtlTrailingPunctRegexp = regexp.MustCompile(`(\s*)([-–—.,;:!?…،؛؟]+)$`)
+	rtlNumberRegexp        = regexp.MustCompile(`\d+([.,:/]\d+)*`)
+)
+
+// IsRTLLanguage returns true if a language is written right-to-left
+// The language is a code as "ar", "he-IL" or "az-Arab", the script
+// subtag, if any, decides the direction
+func IsRTLLanguage(lang string) bool {
+	subtags := strings.FieldsFunc(strings.ToLower(lang), func(r rune) bool { return r == '-' || r == '_' })
+	if len(subtags) == 0 {
+		return false
+	}
+	for _, tag := range subtags[1:] {
+		if len(tag) == 4 {
+			return rtlScripts[tag]
+		}
+	}
+	return rtlLanguages[subtags[0]]
+}
+
+// SetTargetLanguage sets the language of the translation
+// The default line splitter breaks the lines with its BreakRules
+func (this *SubtitleSRT) SetTargetLanguage(lang string) {
+	defer this.beginEdit("SetTargetLanguage", lang)(nil)
+	this.metadata.TargetLanguage = lang
+}
+
+// GetTargetLanguage returns the language of the translation
+func (this *SubtitleSRT) GetTargetLanguage() string {
+	return this.metadata.TargetLanguage
+}
+
+// SetRTLOptions sets the handling of the translated lines
+// when the target language is written right-to-left
+func (this *SubtitleSRT) SetRTLOptions(opts RTLOptions) {
+	defer this.beginEdit("SetRTLOptions", opts)(nil)
+	this.rtlOptions = opts
+}
+
+// GetRTLOptions returns the handling of right-to-left translated lines
+func (this *SubtitleSRT) GetRTLOptions() RTLOptions {
+	return this.rtlOptions
+}
+
+// translatedLineForOutput returns a translated line as it is printed:
+// with the RTLOptions applied if the target language is right-to-left
+func (this *SubtitleSRT) translatedLineForOutput(line string) string {
+	if line == "" || !IsRTLLanguage(this.metadata.TargetLanguage) {
+		return line
+	}
+	return this.rtlOptions.apply(line)
+}
+
+// apply returns the line with the RTLOptions applied
+func (o RTLOptions) apply(line string) string {
+	if o.IsolateNumbers {
+		line = rtlNumberRegexp.ReplaceAllString(line, lrm+"$0"+lrm)
+	}
+	if o.MovePunctuation {
+		var leading, trailing string
+		if loc := rtlLeadingPunctRegexp.FindStringSubmatch(line); loc != nil && loc[0] != line {
+			line = line[len(loc[0]):]
+			leading = loc[2] + loc[1]
+		}
+		if loc := rtlTrailingPunctRegexp.FindStringSubmatch(line); loc != nil && loc[0] != line {
+			line = line[:len(line)-len(loc[0])]
+			trailing = loc[2] + loc[1]
+		}
+		line = trailing + line + leading
+	}
+	switch o.Mark {
+	case RLMMark:
+		line = rlm + line
+	case RLEMark:
+		line = rle + line + pdf
+	}
+	return line
+}
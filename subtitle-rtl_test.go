@@ -0,0 +1,55 @@
+package subtitle
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestIsRTLLanguage(t *testing.T) {
+	for lang, want := range map[string]bool{
+		"ar": true, "he-IL": true, "fa_IR": true, "az-Arab": true,
+		"ku-Latn": false, "es": false, "en-US": false, "": false,
+	} {
+		if have := IsRTLLanguage(lang); have != want {
+			t.Fatalf("IsRTLLanguage(%q): want %v have %v", lang, want, have)
+		}
+	}
+}
+
+func TestRTLOptions(t *testing.T) {
+	opts := RTLOptions{MovePunctuation: true}
+	if have := opts.apply("- שלום, מה שלומך?"); have != "?שלום, מה שלומך -" {
+		t.Fatalf("MovePunctuation: unexpected line %q", have)
+	}
+	opts = RTLOptions{Mark: RLEMark, IsolateNumbers: true}
+	if have := opts.apply("בשעה 10:30"); have != rle+"בשעה "+lrm+"10:30"+lrm+pdf {
+		t.Fatalf("RLEMark, IsolateNumbers: unexpected line %q", have)
+	}
+}
+
+func TestPrintTranslatedSRTRTL(t *testing.T) {
+	var subt SubtitleSRT
+	subt.SetOriginalSrt(strings.NewReader(alignOptionsTestSrt("Hello")))
+	subt.SetTranslatedText("בוקר טוב. שלום. להתראות.")
+	subt.SetRTLOptions(RTLOptions{Mark: RLMMark})
+
+	// No marks until the target language is known to be RTL
+	var out bytes.Buffer
+	subt.PrintTranslatedSRT(&out)
+	if strings.Contains(out.String(), rlm) {
+		t.Fatal("PrintTranslatedSRT(): unexpected RLM without target language")
+	}
+	subt.SetTargetLanguage("he")
+	out.Reset()
+	subt.PrintTranslatedSRT(&out)
+	if n := strings.Count(out.String(), rlm); n != 3 {
+		t.Fatalf("PrintTranslatedSRT(): want 3 RLM have %d", n)
+	}
+	// The lines are not changed
+	for _, l := range subt.GetTranslatedLines() {
+		if strings.Contains(l, rlm) {
+			t.Fatalf("PrintTranslatedSRT(): line changed: %q", l)
+		}
+	}
+}
@@ -0,0 +1,150 @@
+package subtitle
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// ---------------------------------------------
+// Language-specific rules to break text in lines
+// ---------------------------------------------
+
+// The break rules shipped with the package, one file per language
+//
+//go:embed breakrules/*.json
+var breakRulesFiles embed.FS
+
+// BreakRules are the rules of a language to break a text in lines
+//   - NoBreakAfter are the words that must not end a line
+//     (articles, prepositions, conjunctions...)
+//   - BreakBefore are the words a line should rather start with
+//     (conjunctions, relative pronouns...)
+//   - Units must not be separated from the number before them
+//
+// A line is always preferred to end with punctuation.
+// Words are compared in lower case, without opening punctuation.
+// The lists must not change once the BreakRules are used.
+type BreakRules struct {
+	Language     string   `json:"language"`
+	NoBreakAfter []string `json:"noBreakAfter"`
+	BreakBefore  []string `json:"breakBefore"`
+	Units        []string `json:"units"`
+
+	noBreakAfter map[string]bool
+	breakBefore  map[string]bool
+	units        map[string]bool
+	indexOnce    sync.Once
+}
+
+// Regular expressions to classify words
+var (
+	numberRegexp       = regexp.MustCompile(`^[+-]?\d+([.,:]\d+)*$`)
+	openingPunctRegexp = regexp.MustCompile(`^[¿¡"'«“‘(\[]+`)
+)
+
+// LoadBreakRules reads BreakRules in JSON format
+func LoadBreakRules(reader io.Reader) (*BreakRules, error) {
+	rules := new(BreakRules)
+	if err := json.NewDecoder(reader).Decode(rules); err != nil {
+		return nil, err
+	}
+	rules.index()
+	return rules, nil
+}
+
+// GetBreakRules returns the BreakRules shipped for a language
+// The language is an ISO 639-1 code, optionally with region ("es", "pt-BR")
+func GetBreakRules(lang string) (*BreakRules, error) {
+	file, err := breakRulesFiles.Open("breakrules/" + baseLanguage(lang) + ".json")
+	if err != nil {
+		return nil, fmt.Errorf("subtitle: no break rules for language %q", lang)
+	}
+	defer file.Close()
+	return LoadBreakRules(file)
+}
+
+// The shipped BreakRules of the default splitter, loaded once per language
+var (
+	defaultBreakRules      = map[string]*BreakRules{}
+	defaultBreakRulesMutex sync.Mutex
+)
+
+// shippedBreakRules returns the BreakRules shipped for a language,
+// nil if there are none. They are shared, and must not be changed
+func shippedBreakRules(lang string) *BreakRules {
+	lang = baseLanguage(lang)
+	defaultBreakRulesMutex.Lock()
+	defer defaultBreakRulesMutex.Unlock()
+	rules, ok := defaultBreakRules[lang]
+	if !ok {
+		rules, _ = GetBreakRules(lang)
+		defaultBreakRules[lang] = rules
+	}
+	return rules
+}
+
+// baseLanguage returns the language of a code without region, in lower case
+func baseLanguage(lang string) string {
+	lang = strings.ToLower(strings.TrimSpace(lang))
+	if i := strings.IndexAny(lang, "-_"); i >= 0 {
+		lang = lang[:i]
+	}
+	return lang
+}
+
+// index builds the sets of words from the lists, once: the BreakRules
+// can be shared by the splitters running in several goroutines
+func (this *BreakRules) index() {
+	this.indexOnce.Do(this.buildIndex)
+}
+
+// buildIndex builds the sets of words from the lists
+func (this *BreakRules) buildIndex() {
+	toSet := func(words []string) map[string]bool {
+		set := make(map[string]bool, len(words))
+		for _, w := range words {
+			set[strings.ToLower(w)] = true
+		}
+		return set
+	}
+	this.noBreakAfter = toSet(this.NoBreakAfter)
+	this.breakBefore = toSet(this.BreakBefore)
+	this.units = toSet(this.Units)
+}
+
+// normalizeWord returns a word in lower case, without opening punctuation
+func normalizeWord(word string) string {
+	return strings.ToLower(openingPunctRegexp.ReplaceAllString(word, ""))
+}
+
+// IsBadBreak returns true if a line must not end with word
+// when next is the first word of the next line
+func (this *BreakRules) IsBadBreak(word, next string) bool {
+	this.index()
+	if this.noBreakAfter[normalizeWord(word)] {
+		return true
+	}
+	if breakPunctRegexp.MatchString(word) {
+		return false
+	}
+	// Keep number + unit together
+	if next != "" && numberRegexp.MatchString(word) {
+		return this.units[strings.TrimRight(normalizeWord(next), ".,;:!?…")]
+	}
+	return false
+}
+
+// IsGoodBreak returns true if a line should rather end with word
+// when next is the first word of the next line: the word ends
+// with punctuation or the next one is a BreakBefore word
+func (this *BreakRules) IsGoodBreak(word, next string) bool {
+	if this.IsBadBreak(word, next) {
+		return false
+	}
+	return breakPunctRegexp.MatchString(word) || this.breakBefore[normalizeWord(next)]
+}
@@ -23,26 +23,63 @@ func (this *SubtitleSRT) SetOriginalSrt(reader io.Reader) {
 	}
 	// Create the slice and underlying array []translatedLine
 	this.translatedLine = make([]string, len(this.originalLine))
+	this.touchCreated()
 }
 
 // Import the translated text, into the translatedText field
+// The text is split into LineSets with the default AlignOptions
+// Nothing changes if the translation of a locked LineSet is not in the
+// text: SetTranslatedTextWithOptions returns the error of that case
 func (this *SubtitleSRT) SetTranslatedText(txt string) {
-	this.translatedText = prepareString(txt)
-	this.splitTranslatedTextIntoLineSets()
+	this.SetTranslatedTextWithOptions(txt, AlignOptions{})
+}
+
+// Import the translated text, into the translatedText field
+// The text is split into LineSets with the given AlignOptions,
+// that are kept to interpret the translated text afterwards
+func (this *SubtitleSRT) SetTranslatedTextWithOptions(txt string, opts AlignOptions) (err error) {
+	defer this.beginEdit("SetTranslatedTextWithOptions", txt, opts)(&err)
+	if err := opts.Validate(); err != nil {
+		return err
+	}
+	text := this.normalizeTranslated(txt)
+	if this.hasLockedLineSets() {
+		// The locked LineSets are kept, the rest of the text is aligned again
+		previous := this.alignOptions
+		this.alignOptions = opts
+		if err := this.detectLineSetsAroundLocked(text); err != nil {
+			this.alignOptions = previous
+			return err
+		}
+	} else {
+		this.alignOptions = opts
+		this.translatedText = text
+		this.detectLineSets()
+	}
 	for i := range this.lineSet {
 		this.splitTranslatedLineSetIntoLines(i)
 	}
+	return nil
+}
+
+// SetAlignStrategy selects how SetTranslatedText splits the text into LineSets
+// It takes effect the next time the translated text is set
+func (this *SubtitleSRT) SetAlignStrategy(strategy AlignStrategy) {
+	defer this.beginEdit("SetAlignStrategy", strategy)(nil)
+	this.alignStrategy = strategy
 }
 
 // Import the translated text of a LineSet into its translatedSet field
+// The translation of a locked LineSet is not changed
 func (this *SubtitleSRT) SetTranslatedTextOfLineSet(lineSetNumber int, txt string) {
-	// Check that lineSet is in range
-	if lineSetNumber < 0 || lineSetNumber >= len(this.lineSet) {
+	defer this.beginEdit("SetTranslatedTextOfLineSet", lineSetNumber, txt)(nil)
+	// Check that lineSet is in range and not locked
+	if lineSetNumber < 0 || lineSetNumber >= len(this.lineSet) || this.lineSet[lineSetNumber].Locked {
 		// (****) Should raise an error
 		return
 	}
 	// Assign the txt to the translatedSet
-	this.translatedSet[lineSetNumber] = prepareString(txt)
+	this.translatedSet[lineSetNumber] = this.normalizeTranslated(txt)
 	// Split the translation of this lineSetNumber into lines
 	this.splitTranslatedLineSetIntoLines(lineSetNumber)
 	// build the translatedText with the new translatedSet
@@ -57,4 +94,5 @@ func (this *SubtitleSRT) DeleteSubtitleSrt() {
 	this.translatedLine = nil
 	this.translatedSet = nil
 	this.translatedText = ""
+	this.ClearHistory()
 }
@@ -0,0 +1,232 @@
+package subtitle
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// -------------------------------------
+// Timing of the blocks to shot changes
+// -------------------------------------
+
+// ShotChanges are the times of the shot changes of a video, sorted
+type ShotChanges []time.Duration
+
+// The values of a shot change list:
+//   - a frame number (1234)
+//   - seconds (51.426)
+//   - a time (00:00:51.426 or 00:00:51,426)
+//   - a timecode with frames (00:00:51:10)
+var (
+	shotFrameRegexp    = regexp.MustCompile(`^\d+$`)
+	shotSecondsRegexp  = regexp.MustCompile(`^\d+\.\d+$`)
+	shotTimeRegexp     = regexp.MustCompile(`^(\d+):(\d{1,2}):(\d{1,2})[,.](\d{1,3})$`)
+	shotTimecodeRegexp = regexp.MustCompile(`^(\d+):(\d{1,2}):(\d{1,2})[:;](\d{1,2})$`)
+	shotFieldSeparator = regexp.MustCompile(`[\s,;]+`)
+)
+
+// LoadShotChanges reads a shot change list, with a shot change per line:
+// a frame number, seconds, a time or a timecode with frames, at the frame
+// rate (0 means 25). Empty lines and lines starting with # are skipped
+// The lines before the first shot change are headings. A heading of
+// comma, semicolon or tab separated columns selects the column of the
+// shot changes, as in the CSV of the scene detectors:
+//
+//	Scene Number,Start Frame,Start Timecode,Start Time (seconds),End Frame,...
+//
+// The column is the first one named time or timecode, otherwise frame,
+// that is not an end or a length. Without it, the first value is used
+func LoadShotChanges(reader io.Reader, frameRate float64) (ShotChanges, error) {
+	opts := RetimeOptions{FrameRate: frameRate}
+	var shots ShotChanges
+	column, separator := -1, ""
+	scanner := bufio.NewScanner(reader)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var field string
+		if column >= 0 {
+			fields := splitShotColumns(line, separator)
+			if column < len(fields) {
+				field = fields[column]
+			}
+		} else {
+			// Times with a comma are split only by spaces
+			field = strings.Fields(line)[0]
+			if !shotTimeRegexp.MatchString(field) {
+				field = shotFieldSeparator.Split(line, 2)[0]
+			}
+		}
+		shot, err := parseShotChange(field, opts)
+		if err != nil {
+			if len(shots) == 0 {
+				column, separator = shotColumn(line)
+				continue
+			}
+			return nil, fmt.Errorf("subtitle: line %d of the shot changes: %v", n, err)
+		}
+		shots = append(shots, shot)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(shots, func(i, j int) bool { return shots[i] < shots[j] })
+	return shots, nil
+}
+
+// shotColumn returns the column of the shot changes in a heading and
+// the separator of its columns, or -1 if the heading does not name it
+func shotColumn(heading string) (int, string) {
+	separator := ","
+	for _, sep := range []string{"\t", ";"} {
+		if strings.Contains(heading, sep) {
+			separator = sep
+			break
+		}
+	}
+	names := splitShotColumns(heading, separator)
+	if len(names) < 2 {
+		return -1, ""
+	}
+	for _, kind := range []string{"time", "frame"} {
+		for i, name := range names {
+			name = strings.ToLower(name)
+			if strings.Contains(name, kind) && !strings.Contains(name, "end") &&
+				!strings.Contains(name, "length") && !strings.Contains(name, "duration") {
+				return i, separator
+			}
+		}
+	}
+	return -1, ""
+}
+
+// splitShotColumns returns the columns of a line, without spaces or quotes
+func splitShotColumns(line, separator string) []string {
+	fields := strings.Split(line, separator)
+	for i, f := range fields {
+		fields[i] = strings.Trim(strings.TrimSpace(f), `"`)
+	}
+	return fields
+}
+
+// parseShotChange returns the time of a value of a shot change list
+func parseShotChange(field string, opts RetimeOptions) (time.Duration, error) {
+	switch {
+	case shotFrameRegexp.MatchString(field):
+		frame, _ := strconv.Atoi(field)
+		return opts.frames(frame), nil
+	case shotSecondsRegexp.MatchString(field):
+		seconds, _ := strconv.ParseFloat(field, 64)
+		return time.Duration(seconds * float64(time.Second)), nil
+	case shotTimeRegexp.MatchString(field):
+		return parseTime(shotTimeRegexp.FindStringSubmatch(field)[1:5]), nil
+	case shotTimecodeRegexp.MatchString(field):
+		m := shotTimecodeRegexp.FindStringSubmatch(field)
+		frame, _ := strconv.Atoi(m[4])
+		return parseTime([]string{m[1], m[2], m[3], "0"}) + opts.frames(frame), nil
+	}
+	return 0, fmt.Errorf("invalid shot change %q", field)
+}
+
+// SnapOptions are the windows to snap the blocks to the shot changes
+type SnapOptions struct {
+	// FrameRate is the frames per second of the windows (0 means the default, 25)
+	FrameRate float64
+	// InFrames is the max distance of the start of a block to a shot change
+	// to move it to the shot change
+	InFrames int
+	// OutFrames is the max distance of the end of a block to a shot change
+	// to move it to the shot change
+	OutFrames int
+	// OutGapFrames is the gap between the end of a snapped block and the shot change
+	OutGapFrames int
+}
+
+// nearest returns the shot change nearest to t within a window
+func (shots ShotChanges) nearest(t, window time.Duration) (time.Duration, bool) {
+	i := sort.Search(len(shots), func(i int) bool { return shots[i] >= t })
+	best, found := time.Duration(0), false
+	for _, j := range []int{i, i - 1} {
+		if j < 0 || j >= len(shots) {
+			continue
+		}
+		d := shots[j] - t
+		if d < 0 {
+			d = -d
+		}
+		if d <= window {
+			window, best, found = d, shots[j], true
+		}
+	}
+	return best, found
+}
+
+// crossed returns the first shot change after start and before end
+func (shots ShotChanges) crossed(start, end time.Duration) (time.Duration, bool) {
+	i := sort.Search(len(shots), func(i int) bool { return shots[i] > start })
+	if i < len(shots) && shots[i] < end {
+		return shots[i], true
+	}
+	return 0, false
+}
+
+// SnapToShotChanges moves the start and end of the blocks to the shot
+// changes within the windows of the options, and returns the blocks
+// snapped, the blocks that still cross a shot change and, at the end,
+// the snapped blocks that overlap the next one or are overlapped by it
+// The blocks with an invalid time mark are not changed
+func (this *SubtitleSRT) SnapToShotChanges(shots ShotChanges, opts SnapOptions) []BlockChange {
+	defer this.beginEdit("SnapToShotChanges", shots, opts)(nil)
+	var changes []BlockChange
+	frames := RetimeOptions{FrameRate: opts.FrameRate}.frames
+	inWindow, outWindow, outGap := frames(opts.InFrames), frames(opts.OutFrames), frames(opts.OutGapFrames)
+	for b := range this.subtitleBlock {
+		start, end, err := this.subtitleBlock[b].Times()
+		if err != nil {
+			continue
+		}
+		newStart, newEnd := start, end
+		if shot, ok := shots.nearest(start, inWindow); ok && shot < end {
+			newStart = shot
+		}
+		if shot, ok := shots.nearest(end, outWindow); ok && shot-outGap > newStart {
+			newEnd = shot - outGap
+		}
+		old := this.subtitleBlock[b].Timemark
+		if newStart != start || newEnd != end {
+			this.subtitleBlock[b].SetTimes(newStart, newEnd)
+			changes = append(changes, BlockChange{BlockSnapped, b, old, this.subtitleBlock[b].Timemark})
+		}
+		if shot, ok := shots.crossed(newStart, newEnd); ok {
+			changes = append(changes, BlockChange{BlockCrossesShot, b, this.subtitleBlock[b].Timemark, formatTime(shot)})
+		}
+	}
+
+	// A snapped block can overlap the block before or after it
+	snapped := make(map[int]bool)
+	for _, c := range changes {
+		if c.Kind == BlockSnapped {
+			snapped[c.Index] = true
+		}
+	}
+	for b := 0; b+1 < len(this.subtitleBlock); b++ {
+		if !snapped[b] && !snapped[b+1] {
+			continue
+		}
+		_, end, err := this.subtitleBlock[b].Times()
+		next, _, errNext := this.subtitleBlock[b+1].Times()
+		if err == nil && errNext == nil && next < end {
+			old := this.subtitleBlock[b].Timemark
+			changes = append(changes, BlockChange{BlockOverlap, b, old, old})
+		}
+	}
+	return changes
+}
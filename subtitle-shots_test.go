@@ -0,0 +1,97 @@
+package subtitle
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// A shot change list with all the formats, at 25 fps
+const shotsTestList = `# shot changes
+Shots
+75,00:00:03.000
+00:00:06,480
+00:00:08:10
+10.52
+`
+
+func TestLoadShotChanges(t *testing.T) {
+	shots, err := LoadShotChanges(strings.NewReader(shotsTestList), 25)
+	if err != nil {
+		t.Fatalf("LoadShotChanges(): %v", err)
+	}
+	want := ShotChanges{3 * time.Second, 6480 * time.Millisecond, 8400 * time.Millisecond, 10520 * time.Millisecond}
+	if len(shots) != len(want) {
+		t.Fatalf("LoadShotChanges(): want %v have %v", want, shots)
+	}
+	for i := range want {
+		if shots[i] != want[i] {
+			t.Fatalf("LoadShotChanges(): want %v have %v", want, shots)
+		}
+	}
+	if _, err := LoadShotChanges(strings.NewReader("75\nshot\n"), 25); err == nil {
+		t.Fatal("LoadShotChanges(): want error for an invalid line")
+	}
+}
+
+func TestLoadShotChangesColumns(t *testing.T) {
+	lists := map[string]string{
+		// The scene list of a scene detector, the first column is the scene number
+		"scenes": `Timecode List:,00:00:03.000,00:00:06.480
+Scene Number,Start Frame,Start Timecode,Start Time (seconds),End Frame,End Timecode,End Time (seconds),Length (frames)
+1,0,00:00:00.000,0.000,75,00:00:03.000,3.000,75
+2,75,00:00:03.000,3.000,162,00:00:06.480,6.480,87
+3,162,00:00:06.480,6.480,210,00:00:08.400,8.400,48
+`,
+		"frames": "Shot;Frame\n1;0\n2;75\n3;162\n",
+	}
+	for name, list := range lists {
+		shots, err := LoadShotChanges(strings.NewReader(list), 25)
+		if err != nil {
+			t.Fatalf("LoadShotChanges(%s): %v", name, err)
+		}
+		if len(shots) != 3 || shots[1] != 3*time.Second || shots[2] != 6480*time.Millisecond {
+			t.Fatalf("LoadShotChanges(%s): unexpected %v", name, shots)
+		}
+	}
+	if _, err := LoadShotChanges(strings.NewReader("Shot,Frame\n1,75\n2\n"), 25); err == nil {
+		t.Fatal("LoadShotChanges(): want error for a line without the column")
+	}
+}
+
+func TestSnapToShotChanges(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, StatisticalAlignment)
+	shots, _ := LoadShotChanges(strings.NewReader(shotsTestList), 25)
+
+	changes := subt.SnapToShotChanges(shots, SnapOptions{InFrames: 1, OutFrames: 10, OutGapFrames: 2})
+	want := "00:00:01,000 --> 00:00:02,920|00:00:03,500 --> 00:00:06,000|00:00:06,480 --> 00:00:07,000|" +
+		"00:00:07,500 --> 00:00:08,320|00:00:08,500 --> 00:00:11,000|00:00:11,500 --> 00:00:13,000"
+	if have := timemarksOf(&subt); have != want {
+		t.Fatalf("SnapToShotChanges(): want %s have %s", want, have)
+	}
+	var report []string
+	for _, c := range changes {
+		report = append(report, c.String())
+	}
+	if len(changes) != 4 || changes[3].Kind != BlockCrossesShot || changes[3].Index != 4 || changes[3].New != "00:00:10,520" {
+		t.Fatalf("SnapToShotChanges(): unexpected changes\n%s", strings.Join(report, "\n"))
+	}
+}
+
+func TestSnapToShotChangesOverlap(t *testing.T) {
+	var subt SubtitleSRT
+	subt.SetOriginalSrt(strings.NewReader(`1
+00:00:01,000 --> 00:00:03,000
+One.
+
+2
+00:00:03,100 --> 00:00:05,000
+Two.
+`))
+	// The start of block 2 is snapped before the end of block 1
+	changes := subt.SnapToShotChanges(ShotChanges{2900 * time.Millisecond}, SnapOptions{InFrames: 5})
+	if len(changes) != 3 || changes[1].Kind != BlockSnapped || changes[1].Index != 1 ||
+		changes[2].Kind != BlockOverlap || changes[2].Index != 0 {
+		t.Fatalf("SnapToShotChanges(): unexpected changes %v", changes)
+	}
+}
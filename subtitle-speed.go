@@ -0,0 +1,179 @@
+package subtitle
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// -------------------------------------------------
+// Reading speed of the subtitle blocks (CPS and WPM)
+// -------------------------------------------------
+
+// ReadingSpeed is the reading speed of a subtitle block, in
+// characters per second and words per minute of the original and
+// the translated lines. The speeds are 0 if the block has no valid
+// duration (Duration <= 0)
+type ReadingSpeed struct {
+	Block         int
+	Duration      time.Duration
+	OriginalCPS   float64
+	TranslatedCPS float64
+	OriginalWPM   float64
+	TranslatedWPM float64
+}
+
+// SpeedDistribution summarises a reading speed over the blocks
+// with a valid duration and some text
+type SpeedDistribution struct {
+	Count  int
+	Min    float64
+	Max    float64
+	Mean   float64
+	Median float64
+	P90    float64
+	P95    float64
+}
+
+// ReadingSpeedStats are the distributions of the reading speeds of a file
+type ReadingSpeedStats struct {
+	OriginalCPS   SpeedDistribution
+	TranslatedCPS SpeedDistribution
+	OriginalWPM   SpeedDistribution
+	TranslatedWPM SpeedDistribution
+}
+
+// ReadingSpeedLimit is the max reading speed of a block
+//   - MaxCPS is the max characters per second, 0 for no limit
+//   - MaxWPM is the max words per minute, 0 for no limit
+//   - Original checks the original lines instead of the translated ones
+type ReadingSpeedLimit struct {
+	MaxCPS   float64
+	MaxWPM   float64
+	Original bool
+}
+
+// CalculateReadingSpeedOfBlock returns the reading speed of a subtitle block
+// The chars are counted in each line, the line breaks are not counted
+func (this *SubtitleSRT) CalculateReadingSpeedOfBlock(theBlock int) ReadingSpeed {
+	if theBlock < 0 || theBlock >= len(this.subtitleBlock) {
+		return ReadingSpeed{Block: -1}
+	}
+	init, last := this.blockLines(theBlock)
+	return this.calculateReadingSpeed(theBlock, init, last)
+}
+
+// CalculateReadingSpeeds returns the reading speed of all the subtitle blocks
+func (this *SubtitleSRT) CalculateReadingSpeeds() []ReadingSpeed {
+	speeds := make([]ReadingSpeed, len(this.subtitleBlock))
+	init := 0
+	for b, sbt := range this.subtitleBlock {
+		speeds[b] = this.calculateReadingSpeed(b, init, init+sbt.Nlines-1)
+		init += sbt.Nlines
+	}
+	return speeds
+}
+
+// calculateReadingSpeed returns the reading speed of a block and its lines
+func (this *SubtitleSRT) calculateReadingSpeed(theBlock, init, last int) ReadingSpeed {
+	speed := ReadingSpeed{Block: theBlock, Duration: this.subtitleBlock[theBlock].Duration()}
+	if speed.Duration <= 0 {
+		return speed
+	}
+	var origChars, origWords, trChars, trWords int
+	for i, translated := range this.translatedLinesOf(init, last-init+1) {
+		origChars += countChars(this.originalLine[init+i])
+		origWords += countWords(this.originalLine[init+i])
+		trChars += countChars(translated)
+		trWords += countWords(translated)
+	}
+	seconds := speed.Duration.Seconds()
+	speed.OriginalCPS = float64(origChars) / seconds
+	speed.TranslatedCPS = float64(trChars) / seconds
+	speed.OriginalWPM = float64(origWords) * 60 / seconds
+	speed.TranslatedWPM = float64(trWords) * 60 / seconds
+	return speed
+}
+
+// CalculateReadingSpeedStats returns the distributions of the reading
+// speeds of the file. The blocks without text are not considered
+func (this *SubtitleSRT) CalculateReadingSpeedStats() ReadingSpeedStats {
+	var origCPS, trCPS, origWPM, trWPM []float64
+	for _, s := range this.CalculateReadingSpeeds() {
+		if s.Duration <= 0 {
+			continue
+		}
+		if s.OriginalCPS > 0 {
+			origCPS = append(origCPS, s.OriginalCPS)
+			origWPM = append(origWPM, s.OriginalWPM)
+		}
+		if s.TranslatedCPS > 0 {
+			trCPS = append(trCPS, s.TranslatedCPS)
+			trWPM = append(trWPM, s.TranslatedWPM)
+		}
+	}
+	return ReadingSpeedStats{
+		OriginalCPS:   calculateDistribution(origCPS),
+		TranslatedCPS: calculateDistribution(trCPS),
+		OriginalWPM:   calculateDistribution(origWPM),
+		TranslatedWPM: calculateDistribution(trWPM),
+	}
+}
+
+// calculateDistribution returns the distribution of a list of values
+func calculateDistribution(values []float64) SpeedDistribution {
+	if len(values) == 0 {
+		return SpeedDistribution{}
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	sum := 0.0
+	for _, v := range sorted {
+		sum += v
+	}
+	// The percentile p is the value below which p% of the values are (nearest rank)
+	percentile := func(p float64) float64 {
+		return sorted[int(math.Ceil(p/100*float64(len(sorted))))-1]
+	}
+	median := sorted[len(sorted)/2]
+	if len(sorted)%2 == 0 {
+		median = (sorted[len(sorted)/2-1] + median) / 2
+	}
+	return SpeedDistribution{
+		Count:  len(sorted),
+		Min:    sorted[0],
+		Max:    sorted[len(sorted)-1],
+		Mean:   sum / float64(len(sorted)),
+		Median: median,
+		P90:    percentile(90),
+		P95:    percentile(95),
+	}
+}
+
+// GetBlocksOverReadingSpeed returns the reading speed of the blocks over
+// the limit. A block with text but no valid duration is always over it
+func (this *SubtitleSRT) GetBlocksOverReadingSpeed(limit ReadingSpeedLimit) []ReadingSpeed {
+	var over []ReadingSpeed
+	init := 0
+	for b, sbt := range this.subtitleBlock {
+		last := init + sbt.Nlines - 1
+		s := this.calculateReadingSpeed(b, init, last)
+		cps, wpm := s.TranslatedCPS, s.TranslatedWPM
+		lines := this.translatedLine[init : last+1]
+		if limit.Original {
+			cps, wpm = s.OriginalCPS, s.OriginalWPM
+			lines = this.originalLine[init : last+1]
+		}
+		init = last + 1
+
+		switch {
+		case s.Duration <= 0:
+			if joinStrings(lines...) != "" {
+				over = append(over, s)
+			}
+		case limit.MaxCPS > 0 && cps > limit.MaxCPS, limit.MaxWPM > 0 && wpm > limit.MaxWPM:
+			over = append(over, s)
+		}
+	}
+	return over
+}
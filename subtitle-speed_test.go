@@ -0,0 +1,37 @@
+package subtitle
+
+import (
+	"math"
+	"testing"
+)
+
+func TestReadingSpeed(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, StatisticalAlignment)
+
+	// 55 chars and 9 words in 2 seconds
+	s := subt.CalculateReadingSpeedOfBlock(0)
+	if s.OriginalCPS != 27.5 || s.OriginalWPM != 270 {
+		t.Fatalf("CalculateReadingSpeedOfBlock(0): unexpected %+v", s)
+	}
+	if s.TranslatedCPS == 0 || s.TranslatedWPM == 0 {
+		t.Fatalf("CalculateReadingSpeedOfBlock(0): no translated speed %+v", s)
+	}
+	if speeds := subt.CalculateReadingSpeeds(); len(speeds) != subt.CountBlocks() || speeds[2].OriginalCPS != 14 {
+		t.Fatalf("CalculateReadingSpeeds(): unexpected %+v", speeds)
+	}
+
+	// The empty block is not in the distribution
+	stats := subt.CalculateReadingSpeedStats()
+	if stats.OriginalCPS.Count != 5 || stats.OriginalCPS.Max != 27.5 || stats.OriginalCPS.Min > stats.OriginalCPS.Median {
+		t.Fatalf("CalculateReadingSpeedStats(): unexpected %+v", stats.OriginalCPS)
+	}
+	// 13 words in 2.5 seconds
+	if math.Abs(stats.OriginalWPM.Max-312) > 1e-9 {
+		t.Fatalf("CalculateReadingSpeedStats(): unexpected %+v", stats.OriginalWPM)
+	}
+
+	over := subt.GetBlocksOverReadingSpeed(ReadingSpeedLimit{MaxCPS: 27, Original: true})
+	if len(over) != 1 || over[0].Block != 0 {
+		t.Fatalf("GetBlocksOverReadingSpeed(): want block 0, have %+v", over)
+	}
+}
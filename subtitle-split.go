@@ -0,0 +1,547 @@
+package subtitle
+
+import (
+	"math"
+	"regexp"
+	"strings"
+)
+
+// ---------------------------------------------------------
+// Splitters and constraints to split a LineSet into lines
+// ---------------------------------------------------------
+
+// A LineSplitter distributes the translated text of a LineSet into lines
+// targets holds the desired width of each line, 0 for the lines that
+// must be empty (the original line is empty). It returns one line per target,
+// and all the text must be in the lines
+type LineSplitter interface {
+	SplitLines(text string, targets []float64) []string
+}
+
+// SetLineSplitter selects the LineSplitter used to split LineSets into lines
+// nil selects the default, ProportionalSplitter with the BreakRules shipped
+// for the target language, if any
+func (this *SubtitleSRT) SetLineSplitter(splitter LineSplitter) {
+	this.journalCall("SetLineSplitter", newJournalPlugin(splitter))
+	this.lineSplitter = splitter
+}
+
+// getLineSplitter returns the LineSplitter in use
+func (this *SubtitleSRT) getLineSplitter() LineSplitter {
+	if this.lineSplitter == nil {
+		return ProportionalSplitter{Rules: shippedBreakRules(this.metadata.TargetLanguage)}
+	}
+	return this.lineSplitter
+}
+
+// ProportionalSplitter is the greedy splitter: it fills the lines in order,
+// carrying the excess of each line to the next one, and choosing for each
+// line the closest break point to the target (before or after it)
+// With Rules, a bad break is avoided (going back to a previous break point
+// if both are bad), and a good break is preferred if it is at most
+// goodBreakTolerance chars farther from the target
+type ProportionalSplitter struct {
+	Rules *BreakRules
+}
+
+// Max extra deviation (chars) accepted to break at a good break point
+const goodBreakTolerance = 5
+
+// SplitLines splits the text greedily, line by line
+func (this ProportionalSplitter) SplitLines(text string, targets []float64) []string {
+	// Scripts written without spaces are split by segments
+	if hasNoSpaceScript(text) {
+		return this.splitSegments(text, targets)
+	}
+
+	lines := make([]string, len(targets))
+	data := text
+	excess := 0.0
+	last := len(targets) - 1
+
+	for i := range targets {
+		// Set target size for the translated line
+		target := targets[i] - excess
+		newLine := ""
+
+		if i == last {
+			// If this is the last line, output the rest of the data
+			newLine = data
+			excess = float64(countChars(newLine)) - target + 1.0
+		} else if targets[i] == 0 {
+			// If the original line is empty, output empty data and keep excess
+			newLine = ""
+		} else if data == "" {
+			// if the remaining string is "", return an empty string
+			newLine = ""
+			excess = -target
+			// (****) and maybe raise a warning here!!
+		} else {
+			var chars int
+			var subStrMax, subStrMin string
+			// Find the next "split point" after "target" characters, defined as
+			// <chars>{target-1} + <no-sep>{+} + <sep>{+}
+			if target > 1 {
+				chars = int(target + 0.5)
+			}
+			// Get the substring until the next separator after {chars}
+			// Note: It must be {chars} grapheme clusters, not bytes nor runes
+			if prefix := graphemePrefix(data, chars); countGraphemes(prefix) == chars {
+				rest := data[len(prefix):]
+				if end := strings.IndexFunc(rest, isBreakingSpace); end >= 0 {
+					rest = rest[:end]
+				}
+				subStrMax = prefix + rest
+			}
+			if subStrMax == "" {
+				subStrMax = data
+			}
+			// Get the substring until the prev separator before .{chars}
+			subStrMin = trimLastWord(subStrMax)
+
+			// Now, let's apply euristic rules to define what to return...
+			// ...
+			// If subStrMin=="", output is subStrMax (so, min one word)
+			// in other case, return the closest to target (chars)
+			newLine, excess = ClosestNotEmptyString(target, subStrMin, subStrMax)
+			// Then, apply the language rules to the two candidates
+			if this.Rules != nil {
+				newLine, excess = this.applyBreakRules(target, data, newLine, subStrMin, subStrMax)
+			}
+		}
+		// Now, update the output and data
+		lines[i] = newLine
+		data = strings.TrimSpace(strings.TrimPrefix(data, newLine))
+	}
+	return lines
+}
+
+// splitSegments splits the text greedily by segments (words, CJK chars,
+// Thai clusters), choosing the closest break point to the target as in
+// SplitLines. The BreakRules do not apply to scripts written without spaces
+func (this ProportionalSplitter) splitSegments(text string, targets []float64) []string {
+	lines := make([]string, len(targets))
+	segs := segmentText(text)
+	p := 0
+	excess := 0.0
+	last := len(targets) - 1
+
+	for i := range targets {
+		target := targets[i] - excess
+		switch {
+		case i == last:
+			// The last line gets the rest of the data
+			lines[i] = joinSegments(segs[p:])
+			p = len(segs)
+		case targets[i] == 0:
+			// Empty original line, keep the excess
+		case p == len(segs):
+			excess = -target
+		default:
+			// The first break point at or after target chars, and the previous one
+			q, chars := p, 0
+			for q < len(segs) && (q == p || float64(chars) < target) {
+				if q > p && segs[q].space {
+					chars++
+				}
+				chars += countChars(segs[q].text)
+				q++
+			}
+			subStrMax := joinSegments(segs[p:q])
+			subStrMin := ""
+			if q-1 > p {
+				subStrMin = joinSegments(segs[p : q-1])
+			}
+			lines[i], excess = ClosestNotEmptyString(target, subStrMin, subStrMax)
+			if lines[i] == subStrMax {
+				p = q
+			} else {
+				p = q - 1
+			}
+		}
+	}
+	return lines
+}
+
+// applyBreakRules chooses between the two candidate lines (subStrMin and
+// subStrMax) with the BreakRules, newLine being the closest to target
+func (this ProportionalSplitter) applyBreakRules(target float64, data, newLine, subStrMin, subStrMax string) (string, float64) {
+	other := subStrMin
+	if newLine == subStrMin {
+		other = subStrMax
+	}
+	if other == "" || other == newLine {
+		return newLine, float64(countChars(newLine)) - target
+	}
+	// The words are cut at the breaking spaces, as the lines
+	lastAndNext := func(line string) (string, string) {
+		words := segmentText(line)
+		next := segmentText(strings.TrimPrefix(data, line))
+		if len(words) == 0 {
+			return "", ""
+		}
+		if len(next) == 0 {
+			return words[len(words)-1].text, ""
+		}
+		return words[len(words)-1].text, next[0].text
+	}
+	lw, nw := lastAndNext(newLine)
+	lo, no := lastAndNext(other)
+	devLine := math.Abs(float64(countChars(newLine)) - target)
+	devOther := math.Abs(float64(countChars(other)) - target)
+
+	if this.Rules.IsBadBreak(lw, nw) && !this.Rules.IsBadBreak(lo, no) {
+		newLine = other
+	} else if this.Rules.IsBadBreak(lw, nw) {
+		// Both are bad, go back to the previous break that is not bad
+		prefixes := breakPrefixes(subStrMin)
+		for k := len(prefixes) - 1; k >= 0; k-- {
+			if !this.Rules.IsBadBreak(lastAndNext(prefixes[k])) {
+				newLine = prefixes[k]
+				break
+			}
+		}
+	} else if !this.Rules.IsGoodBreak(lw, nw) && this.Rules.IsGoodBreak(lo, no) && devOther-devLine <= goodBreakTolerance {
+		newLine = other
+	}
+	return newLine, float64(countChars(newLine)) - target
+}
+
+// trimLastWord returns the text without its last word
+// and the breaking spaces before it, "" if it is a single word
+func trimLastWord(text string) string {
+	end := strings.LastIndexFunc(text, isBreakingSpace)
+	if end < 0 {
+		return ""
+	}
+	return strings.TrimRightFunc(text[:end], isBreakingSpace)
+}
+
+// breakPrefixes returns the prefixes of the text that end
+// before a breaking space, so that the lines are prefixes of the text
+func breakPrefixes(text string) []string {
+	var prefixes []string
+	inSpace := false
+	for i, r := range text {
+		if isBreakingSpace(r) {
+			if !inSpace && i > 0 {
+				prefixes = append(prefixes, text[:i])
+			}
+			inSpace = true
+		} else {
+			inSpace = false
+		}
+	}
+	return prefixes
+}
+
+// lastWord returns the last of the words, or ""
+func lastWord(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+	return words[len(words)-1]
+}
+
+// OptimalSplitter considers all the break points of the text at once
+// (in the spirit of Knuth-Plass) and chooses the ones that minimise the
+// total squared deviation from the targets plus penalties:
+//   - PunctuationBonus is subtracted when a line ends with punctuation
+//   - ConjunctionBonus is subtracted when the next line starts with
+//     a BreakBefore word of the Rules
+//   - BadBreakPenalty is added when a line ends with a bad break word: a
+//     NoBreakAfter word or a number before its unit if there are Rules,
+//     otherwise a word of one or two letters, such as "a" or "de"
+//   - EmptyLinePenalty is added when a line with a target gets no text
+//
+// Penalties are in squared chars. MaxCells bounds the size of the problem
+// (words x lines), bigger LineSets fall back to the ProportionalSplitter
+type OptimalSplitter struct {
+	Rules            *BreakRules
+	PunctuationBonus float64
+	ConjunctionBonus float64
+	BadBreakPenalty  float64
+	EmptyLinePenalty float64
+	MaxCells         int
+}
+
+// NewOptimalSplitter returns an OptimalSplitter with the default penalties
+func NewOptimalSplitter() *OptimalSplitter {
+	return &OptimalSplitter{
+		PunctuationBonus: 25,
+		ConjunctionBonus: 15,
+		BadBreakPenalty:  50,
+		EmptyLinePenalty: 100,
+		MaxCells:         200000,
+	}
+}
+
+// Punctuation at the end of a word that makes a good break point
+var breakPunctRegexp = regexp.MustCompile(`[.,;:!?…]["'»”’)\]]*$`)
+
+// SplitLines splits the text with dynamic programming over all break points
+func (this *OptimalSplitter) SplitLines(text string, targets []float64) []string {
+	// The break points are between segments: words, CJK chars, Thai clusters
+	segs := segmentText(text)
+	words := make([]string, len(segs))
+	for i, s := range segs {
+		words[i] = s.text
+	}
+	lines := make([]string, len(targets))
+	if len(targets) == 0 {
+		return lines
+	}
+
+	// The lines that may have text: the ones with a target
+	// If none, all the text goes to the last line
+	var usable []int
+	for i, t := range targets {
+		if t > 0 {
+			usable = append(usable, i)
+		}
+	}
+	if len(usable) == 0 {
+		lines[len(lines)-1] = text
+		return lines
+	}
+	if this.MaxCells > 0 && len(words)*len(usable) > this.MaxCells {
+		return ProportionalSplitter{}.SplitLines(text, targets)
+	}
+
+	// Chars of words[i:j] as a line, from the accumulated chars
+	// (a segment counts the space before it, if any)
+	space := func(i int) int {
+		if i > 0 && segs[i].space {
+			return 1
+		}
+		return 0
+	}
+	acc := make([]int, len(words)+1)
+	for i, w := range words {
+		acc[i+1] = acc[i] + countChars(w) + space(i)
+	}
+	width := func(i, j int) int {
+		if i == j {
+			return 0
+		}
+		return acc[j] - acc[i] - space(i)
+	}
+	lineCost := func(i, j int, target float64) float64 {
+		if i == j {
+			return target*target + this.EmptyLinePenalty
+		}
+		d := float64(width(i, j)) - target
+		c := d * d
+		if j < len(words) {
+			// The last line of the text has no break
+			switch word, next := words[j-1], words[j]; {
+			case this.isBadBreak(word, next):
+				c += this.BadBreakPenalty
+			case breakPunctRegexp.MatchString(word):
+				c -= this.PunctuationBonus
+			case this.Rules != nil && this.Rules.IsGoodBreak(word, next):
+				c -= this.ConjunctionBonus
+			}
+		}
+		return c
+	}
+
+	// cost[k][j] is the min cost of the first k usable lines with words[:j]
+	n := len(words)
+	cost := make([][]float64, len(usable)+1)
+	from := make([][]int, len(usable)+1)
+	for k := range cost {
+		cost[k] = make([]float64, n+1)
+		from[k] = make([]int, n+1)
+		for j := range cost[k] {
+			cost[k][j] = math.Inf(1)
+		}
+	}
+	cost[0][0] = 0
+	for k := 1; k <= len(usable); k++ {
+		target := targets[usable[k-1]]
+		// Lines far wider than the target are not considered
+		maxWidth := 3*target + 20
+		for j := 0; j <= n; j++ {
+			for i := j; i >= 0 && (i == j || float64(width(i, j)) <= maxWidth); i-- {
+				if math.IsInf(cost[k-1][i], 1) {
+					continue
+				}
+				c := cost[k-1][i] + lineCost(i, j, target)
+				if c < cost[k][j] {
+					cost[k][j] = c
+					from[k][j] = i
+				}
+			}
+		}
+	}
+
+	// No solution with lines of a reasonable width
+	if math.IsInf(cost[len(usable)][n], 1) {
+		return ProportionalSplitter{}.SplitLines(text, targets)
+	}
+
+	// Backtrack from the end
+	for k, j := len(usable), n; k > 0; k-- {
+		i := from[k][j]
+		lines[usable[k-1]] = joinSegments(segs[i:j])
+		j = i
+	}
+	return lines
+}
+
+// isBadBreak returns true if a line should not end with the word
+// when the next line starts with next
+func (this *OptimalSplitter) isBadBreak(word, next string) bool {
+	if this.Rules != nil {
+		return this.Rules.IsBadBreak(word, next)
+	}
+	return countGraphemes(word) <= 2 && !breakPunctRegexp.MatchString(word) && !hasNoSpaceScript(word)
+}
+
+// ---------------------------------------------------
+// Constraints applied when a LineSet is split in lines
+// ---------------------------------------------------
+
+// SplitOptions constrains the translated lines produced by the splitter
+//   - MaxCharsPerLine is the max width of a translated line
+//   - MaxLinesPerBlock is the max number of non empty translated lines
+//     of a SubtitleBlock, the lines after it are left empty
+//
+// A zero value means no limit
+type SplitOptions struct {
+	MaxCharsPerLine  int
+	MaxLinesPerBlock int
+}
+
+// SetSplitOptions sets the constraints of the lines
+// They take effect the next time a LineSet is split into lines
+func (this *SubtitleSRT) SetSplitOptions(opts SplitOptions) {
+	defer this.beginEdit("SetSplitOptions", opts)(nil)
+	this.splitOptions = opts
+}
+
+// GetSplitOptions returns the constraints of the lines
+func (this *SubtitleSRT) GetSplitOptions() SplitOptions {
+	return this.splitOptions
+}
+
+// GetLinesOverConstraints returns the translated lines that do not
+// honour SplitOptions: too wide, or beyond MaxLinesPerBlock and not empty
+func (this *SubtitleSRT) GetLinesOverConstraints() []int {
+	var lines []int
+	position := this.linePositionsInBlocks()
+	for i, l := range this.translatedLine {
+		if this.splitOptions.MaxCharsPerLine > 0 && lineWidth(l) > this.splitOptions.MaxCharsPerLine {
+			lines = append(lines, i)
+		} else if this.splitOptions.MaxLinesPerBlock > 0 && position[i] >= this.splitOptions.MaxLinesPerBlock && l != "" {
+			lines = append(lines, i)
+		}
+	}
+	return lines
+}
+
+// linePositionsInBlocks returns, for each line, its position
+// in its SubtitleBlock (0 for the first line of the block)
+func (this *SubtitleSRT) linePositionsInBlocks() []int {
+	position := make([]int, 0, len(this.originalLine))
+	for _, sbt := range this.subtitleBlock {
+		for i := 0; i < sbt.Nlines; i++ {
+			position = append(position, i)
+		}
+	}
+	return position
+}
+
+// fitLineSetToConstraints reflows the translated lines of a LineSet
+// so that they honour SplitOptions. The words of a line that is too wide
+// go to the next line, the ones of the last line go back to the previous
+// lines with room left. Lines that still do not fit are left as they are,
+// and reported by GetLinesOverConstraints
+func (this *SubtitleSRT) fitLineSetToConstraints(theLineSet int) {
+	opts := this.splitOptions
+	if opts.MaxCharsPerLine <= 0 && opts.MaxLinesPerBlock <= 0 {
+		return
+	}
+	init := this.lineSet[theLineSet].InitLine
+	last := this.lineSet[theLineSet].LastLine
+	position := this.linePositionsInBlocks()
+
+	// The usable lines: not empty in the original, and within MaxLinesPerBlock
+	var usable []int
+	for i := init; i <= last; i++ {
+		if this.originalLine[i] != "" && (opts.MaxLinesPerBlock <= 0 || position[i] < opts.MaxLinesPerBlock) {
+			usable = append(usable, i)
+		}
+	}
+	if len(usable) == 0 {
+		return
+	}
+
+	// Words (segments) of each usable line, the words of the other lines
+	// go to the next usable line (or to the last one)
+	texts := make([][]string, len(usable))
+	n := 0
+	for i := init; i <= last; i++ {
+		for n < len(usable)-1 && usable[n] < i {
+			n++
+		}
+		texts[n] = append(texts[n], this.translatedLine[i])
+	}
+	words := make([][]segment, len(usable))
+	for n := range texts {
+		words[n] = segmentText(joinStrings(texts[n]...))
+	}
+
+	fits := func(w []segment) bool {
+		return opts.MaxCharsPerLine <= 0 || lineWidth(joinSegments(w)) <= opts.MaxCharsPerLine
+	}
+
+	// Forward: overflow of a line goes to the beginning of the next one
+	for n := 0; n < len(words)-1; n++ {
+		for len(words[n]) > 1 && !fits(words[n]) {
+			k := len(words[n]) - 1
+			if len(words[n+1]) > 0 {
+				words[n+1][0].space = needsSpace(words[n][k].text, words[n+1][0].text)
+			}
+			words[n+1] = append([]segment{words[n][k]}, words[n+1]...)
+			words[n] = words[n][:k]
+		}
+	}
+	// Backward: overflow of a line goes to the end of the previous one,
+	// making room there by moving its first words further back if needed
+	var makeRoom func(n int, w segment) bool
+	makeRoom = func(n int, w segment) bool {
+		for !fits(appendSegment(words[n][:len(words[n]):len(words[n])], w)) {
+			if n == 0 || len(words[n]) == 0 || !makeRoom(n-1, words[n][0]) {
+				return false
+			}
+			words[n-1] = appendSegment(words[n-1], words[n][0])
+			words[n] = words[n][1:]
+		}
+		return true
+	}
+	for n := len(words) - 1; n > 0; n-- {
+		for len(words[n]) > 1 && !fits(words[n]) && makeRoom(n-1, words[n][0]) {
+			words[n-1] = appendSegment(words[n-1], words[n][0])
+			words[n] = words[n][1:]
+		}
+	}
+
+	// Store the lines
+	for i := init; i <= last; i++ {
+		this.translatedLine[i] = ""
+	}
+	for n, l := range usable {
+		this.translatedLine[l] = joinSegments(words[n])
+	}
+}
+
+// appendSegment appends the first segment of a line to the segments
+// of the previous line, with a space between them if needed
+func appendSegment(segs []segment, first segment) []segment {
+	if len(segs) > 0 {
+		first.space = needsSpace(segs[len(segs)-1].text, first.text)
+	}
+	return append(segs, first)
+}
@@ -0,0 +1,288 @@
+package subtitle
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+const splitTestSrt = `1
+00:00:01,000 --> 00:00:04,000
+I know.
+You are right, I was wrong.
+Sorry.
+
+2
+00:00:04,500 --> 00:00:06,000
+Let's go home.
+`
+
+const splitTestTxt = `Ya lo sé, ya lo sé. Tienes toda la razón, yo estaba equivocado. Perdóname, de verdad. Vámonos a casa.`
+
+// newSplitTestSubtitle loads splitTestSrt with SplitOptions
+func newSplitTestSubtitle(t *testing.T, opts SplitOptions) SubtitleSRT {
+	var subt SubtitleSRT
+	subt.SetOriginalSrt(strings.NewReader(splitTestSrt))
+	subt.SetSplitOptions(opts)
+	subt.SetTranslatedText(splitTestTxt)
+	if !subt.IsTranslationConsistent() {
+		t.Fatalf("SplitOptions %+v: Translation is not consistent", opts)
+	}
+	return subt
+}
+
+func TestSplitMaxCharsPerLine(t *testing.T) {
+	subt := newSplitTestSubtitle(t, SplitOptions{MaxCharsPerLine: 30})
+	for i, l := range subt.GetTranslatedLines() {
+		if lineWidth(l) > 30 {
+			t.Fatalf("MaxCharsPerLine 30: Line %d is %d chars: '%s'", i, lineWidth(l), l)
+		}
+	}
+	if lines := subt.GetLinesOverConstraints(); len(lines) != 0 {
+		t.Fatalf("MaxCharsPerLine 30: want no lines over constraints, have %v", lines)
+	}
+}
+
+func TestSplitMaxLinesPerBlock(t *testing.T) {
+	subt := newSplitTestSubtitle(t, SplitOptions{MaxLinesPerBlock: 2})
+	if subt.GetTranslatedLines()[2] != "" {
+		t.Fatalf("MaxLinesPerBlock 2: Line 2: want '' have '%s'", subt.GetTranslatedLines()[2])
+	}
+	if lines := subt.GetLinesOverConstraints(); len(lines) != 0 {
+		t.Fatalf("MaxLinesPerBlock 2: want no lines over constraints, have %v", lines)
+	}
+}
+
+func TestSplitLinesOverConstraints(t *testing.T) {
+	// There is no room for the text in 3 lines of 20 chars:
+	// lines 0 and 1 are filled, and the rest is left in the last line
+	subt := newSplitTestSubtitle(t, SplitOptions{MaxCharsPerLine: 20, MaxLinesPerBlock: 2})
+	lines := subt.GetLinesOverConstraints()
+	if len(lines) != 1 || lines[0] != 3 {
+		t.Fatalf("GetLinesOverConstraints(): want [3] have %v", lines)
+	}
+	for i := 0; i < 2; i++ {
+		if l := subt.GetTranslatedLines()[i]; lineWidth(l) > 20 || l == "" {
+			t.Fatalf("MaxCharsPerLine 20: Line %d: unexpected '%s'", i, l)
+		}
+	}
+}
+
+// squaredDeviation returns the sum of (width-target)^2 of the lines
+func squaredDeviation(lines []string, targets []float64) float64 {
+	total := 0.0
+	for i, l := range lines {
+		d := float64(lineWidth(l)) - targets[i]
+		total += d * d
+	}
+	return total
+}
+
+func TestOptimalSplitter(t *testing.T) {
+	text := "Era una noche oscura y tormentosa, y de repente sonó un disparo en la casa de al lado."
+	targets := []float64{20, 0, 30, 37}
+
+	greedy := ProportionalSplitter{}.SplitLines(text, targets)
+	optimal := (&OptimalSplitter{}).SplitLines(text, targets)
+
+	if joinStrings(optimal...) != text {
+		t.Fatalf("OptimalSplitter: text is lost: %q", optimal)
+	}
+	if optimal[1] != "" {
+		t.Fatalf("OptimalSplitter: Line 1 has no target and is not empty: %q", optimal[1])
+	}
+	if squaredDeviation(optimal, targets) > squaredDeviation(greedy, targets) {
+		t.Fatalf("OptimalSplitter: worse than greedy: %q vs %q", optimal, greedy)
+	}
+}
+
+func TestOptimalSplitterPenalties(t *testing.T) {
+	text := "Voy a la casa de mi abuela hoy."
+	targets := []float64{16, 14}
+
+	// Without penalties the best break is after "de"
+	lines := (&OptimalSplitter{}).SplitLines(text, targets)
+	if lines[0] != "Voy a la casa de" {
+		t.Fatalf("OptimalSplitter without penalties: Line 0: want 'Voy a la casa de' have '%s'", lines[0])
+	}
+	lines = NewOptimalSplitter().SplitLines(text, targets)
+	if lines[0] != "Voy a la casa" {
+		t.Fatalf("OptimalSplitter: Line 0: want 'Voy a la casa' have '%s'", lines[0])
+	}
+}
+
+func TestSetLineSplitter(t *testing.T) {
+	var subt SubtitleSRT
+	subt.SetOriginalSrt(strings.NewReader(splitTestSrt))
+	subt.SetLineSplitter(NewOptimalSplitter())
+	subt.SetTranslatedText(splitTestTxt)
+	if !subt.IsTranslationConsistent() {
+		t.Fatal("SetLineSplitter(OptimalSplitter): Translation is not consistent")
+	}
+	// The breaks happen after the punctuation
+	for i, l := range subt.GetTranslatedLines() {
+		if !breakPunctRegexp.MatchString(l) {
+			t.Fatalf("SetLineSplitter(OptimalSplitter): Line %d does not end with punctuation: '%s'", i, l)
+		}
+	}
+}
+
+func TestGetBreakRules(t *testing.T) {
+	for _, lang := range []string{"es", "en", "fr", "de", "pt-BR"} {
+		rules, err := GetBreakRules(lang)
+		if err != nil {
+			t.Fatalf("GetBreakRules(%q): %v", lang, err)
+		}
+		if rules.Language != baseLanguage(lang) || len(rules.NoBreakAfter) == 0 {
+			t.Fatalf("GetBreakRules(%q): unexpected rules %+v", lang, rules)
+		}
+	}
+	if _, err := GetBreakRules("xx"); err == nil {
+		t.Fatal("GetBreakRules(\"xx\"): want error")
+	}
+}
+
+func TestDefaultLineSplitterRules(t *testing.T) {
+	var subt SubtitleSRT
+	for lang, want := range map[string]string{"": "", "xx": "", "es-ES": "es", "fr": "fr"} {
+		subt.SetTargetLanguage(lang)
+		splitter, ok := subt.getLineSplitter().(ProportionalSplitter)
+		if !ok || (want == "") != (splitter.Rules == nil) || (splitter.Rules != nil && splitter.Rules.Language != want) {
+			t.Fatalf("getLineSplitter(%q): unexpected %+v", lang, splitter)
+		}
+	}
+	subt.SetLineSplitter(ProportionalSplitter{})
+	if splitter := subt.getLineSplitter().(ProportionalSplitter); splitter.Rules != nil {
+		t.Fatal("getLineSplitter(): want the splitter set, without rules")
+	}
+}
+
+func TestBreakRules(t *testing.T) {
+	rules, _ := GetBreakRules("es")
+	cases := []struct {
+		word, next string
+		bad, good  bool
+	}{
+		{"de", "la", true, false},
+		{"¿De", "verdad?", true, false},
+		{"casa,", "pero", false, true},
+		{"casa", "pero", false, true},
+		{"casa", "grande", false, false},
+		{"25", "km", true, false},
+		{"25", "coches", false, false},
+		{"Sr.", "Pérez", true, false},
+	}
+	for _, c := range cases {
+		if bad := rules.IsBadBreak(c.word, c.next); bad != c.bad {
+			t.Fatalf("IsBadBreak(%q, %q): want %v have %v", c.word, c.next, c.bad, bad)
+		}
+		if good := rules.IsGoodBreak(c.word, c.next); good != c.good {
+			t.Fatalf("IsGoodBreak(%q, %q): want %v have %v", c.word, c.next, c.good, good)
+		}
+	}
+}
+
+func TestBreakRulesConcurrent(t *testing.T) {
+	// The sets of words of rules that are not loaded are built on first use
+	rules := &BreakRules{Language: "en", NoBreakAfter: []string{"the"}, Units: []string{"km"}}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			ProportionalSplitter{Rules: rules}.SplitLines("I have lived in the house for 10 km and years.", []float64{20, 20})
+		}()
+	}
+	wg.Wait()
+	if !rules.IsBadBreak("The", "house") || !rules.IsBadBreak("10", "km") {
+		t.Fatal("IsBadBreak(): want true for the words of the lists")
+	}
+}
+
+func TestSplittersWithBreakRules(t *testing.T) {
+	rules, _ := GetBreakRules("en")
+	text := "I have lived in the house of the Smiths for 10 km and years."
+	targets := []float64{19, 20, 21}
+
+	for _, splitter := range []LineSplitter{ProportionalSplitter{Rules: rules}, &OptimalSplitter{Rules: rules, BadBreakPenalty: 1000}} {
+		lines := splitter.SplitLines(text, targets)
+		if joinStrings(lines...) != text {
+			t.Fatalf("%T: text is lost: %q", splitter, lines)
+		}
+		for i := 0; i < len(lines)-1; i++ {
+			words := strings.Fields(lines[i])
+			next := strings.Fields(lines[i+1])
+			if len(words) > 0 && len(next) > 0 && rules.IsBadBreak(lastWord(words), next[0]) {
+				t.Fatalf("%T: Line %d ends with a bad break: %q", splitter, i, lines)
+			}
+		}
+	}
+}
+
+func TestSplitterWithNoBreakSpaces(t *testing.T) {
+	// The French normalizer puts no-break spaces before the punctuation
+	rules, _ := GetBreakRules("fr")
+	text := GetNormalizer("fr").Normalize("Il dit : le chat de la maison ? Oui ! ...")
+	for _, targets := range [][]float64{{3, 54, 20}, {1, 18, 20}, {8, 14, 20}} {
+		lines := ProportionalSplitter{Rules: rules}.SplitLines(text, targets)
+		if joinStrings(lines...) != text {
+			t.Fatalf("SplitLines(%v): want %q have %q", targets, text, lines)
+		}
+		for i, line := range lines {
+			if strings.HasPrefix(line, "\u00a0") || strings.HasPrefix(line, "\u202f") ||
+				strings.HasPrefix(line, ":") || strings.HasPrefix(line, "?") || strings.HasPrefix(line, "!") {
+				t.Fatalf("SplitLines(%v): line %d breaks at a no-break space %q", targets, i, lines)
+			}
+		}
+	}
+}
+
+// A Japanese translation of splitTestSrt, written without spaces
+const splitTestTxtJa = `わかってる、わかってる。君の言う通りだ、僕が間違っていた。本当にごめん。家に帰ろう。`
+
+func TestSegmentText(t *testing.T) {
+	have := []string{}
+	for _, s := range segmentText("「本当に」ごめん。OK です") {
+		have = append(have, s.text)
+	}
+	want := []string{"「本", "当", "に」", "ご", "め", "ん。", "OK", "で", "す"}
+	if strings.Join(have, "|") != strings.Join(want, "|") {
+		t.Fatalf("segmentText(): want %q have %q", want, have)
+	}
+	// Thai: leading vowels and marks stay with their consonant
+	if units := splitNoSpaceScript("ไม่ใช่"); strings.Join(units, "|") != "ไม่|ใช่" {
+		t.Fatalf("splitNoSpaceScript(Thai): unexpected units %q", units)
+	}
+}
+
+func TestSplitNoSpaceScript(t *testing.T) {
+	for _, splitter := range []LineSplitter{ProportionalSplitter{}, NewOptimalSplitter()} {
+		var subt SubtitleSRT
+		subt.SetOriginalSrt(strings.NewReader(splitTestSrt))
+		subt.SetLineSplitter(splitter)
+		subt.SetTranslatedText(splitTestTxtJa)
+		if !subt.IsTranslationConsistent() {
+			t.Fatalf("Japanese %T: Translation is not consistent", splitter)
+		}
+		for i, l := range subt.GetTranslatedLines() {
+			if l == "" || strings.Contains(l, " ") {
+				t.Fatalf("Japanese %T: Line %d: unexpected line '%s'", splitter, i, l)
+			}
+			if strings.HasPrefix(l, "、") || strings.HasPrefix(l, "。") {
+				t.Fatalf("Japanese %T: Line %d starts with punctuation: '%s'", splitter, i, l)
+			}
+		}
+		if n := subt.CountTranslatedWordsInLineSet(0); n < 20 {
+			t.Fatalf("CountTranslatedWordsInLineSet(): want one word per char, have %d", n)
+		}
+	}
+}
+
+func TestLineWidthEastAsian(t *testing.T) {
+	if w := lineWidth("ごめんA"); w != 7 {
+		t.Fatalf("lineWidth(): want 7 have %d", w)
+	}
+	if n := countChars("ごめん 。 OK"); n != 6 {
+		t.Fatalf("countChars(): want 6 have %d", n)
+	}
+}
@@ -0,0 +1,202 @@
+package subtitle
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// ------------------------------------------------------
+// Shift, stretch and synchronization of the block times
+// ------------------------------------------------------
+
+// FrameRateFactor returns the factor of ScaleTimes to play at the frame
+// rate to the subtitles timed at the frame rate from,
+// e.g. FrameRateFactor(23.976, 25) for the PAL speed-up
+func FrameRateFactor(from, to float64) float64 {
+	return from / to
+}
+
+// ShiftBlocks adds an offset to the times of all the blocks, if any
+func (this *SubtitleSRT) ShiftBlocks(offset time.Duration) (err error) {
+	defer this.beginEdit("ShiftBlocks", offset)(&err)
+	if len(this.subtitleBlock) == 0 {
+		return nil
+	}
+	return this.ShiftBlockRange(0, len(this.subtitleBlock)-1, offset)
+}
+
+// ShiftBlockRange adds an offset to the times of the blocks first to last,
+// both included. The negative times are set to 0
+func (this *SubtitleSRT) ShiftBlockRange(first, last int, offset time.Duration) (err error) {
+	defer this.beginEdit("ShiftBlockRange", first, last, offset)(&err)
+	return this.retimeBlocks(first, last, func(t time.Duration) time.Duration {
+		return t + offset
+	})
+}
+
+// checkScaleFactor returns an error if a factor is not a positive number
+func checkScaleFactor(factor float64) error {
+	if factor <= 0 || math.IsNaN(factor) || math.IsInf(factor, 0) {
+		return fmt.Errorf("subtitle: invalid scale factor %g", factor)
+	}
+	return nil
+}
+
+// ScaleTimes multiplies the times of all the blocks, if any, by a factor
+func (this *SubtitleSRT) ScaleTimes(factor float64) (err error) {
+	defer this.beginEdit("ScaleTimes", factor)(&err)
+	if err := checkScaleFactor(factor); err != nil {
+		return err
+	}
+	if len(this.subtitleBlock) == 0 {
+		return nil
+	}
+	return this.ScaleTimeRange(0, len(this.subtitleBlock)-1, factor)
+}
+
+// ScaleTimeRange multiplies the times of the blocks first to last,
+// both included, by a factor
+func (this *SubtitleSRT) ScaleTimeRange(first, last int, factor float64) (err error) {
+	defer this.beginEdit("ScaleTimeRange", first, last, factor)(&err)
+	if err := checkScaleFactor(factor); err != nil {
+		return err
+	}
+	return this.retimeBlocks(first, last, func(t time.Duration) time.Duration {
+		return time.Duration(float64(t) * factor)
+	})
+}
+
+// SyncByTwoPoints retimes all the blocks linearly, so that the block n
+// starts at t1 and the block m starts at t2
+func (this *SubtitleSRT) SyncByTwoPoints(n int, t1 time.Duration, m int, t2 time.Duration) (err error) {
+	defer this.beginEdit("SyncByTwoPoints", n, t1, m, t2)(&err)
+	if n < 0 || n >= len(this.subtitleBlock) || m < 0 || m >= len(this.subtitleBlock) {
+		return fmt.Errorf("subtitle: invalid blocks %d and %d", n, m)
+	}
+	s1, _, err := this.subtitleBlock[n].Times()
+	if err != nil {
+		return err
+	}
+	s2, _, err := this.subtitleBlock[m].Times()
+	if err != nil {
+		return err
+	}
+	if s1 == s2 {
+		return fmt.Errorf("subtitle: blocks %d and %d start at the same time", n, m)
+	}
+	factor := float64(t2-t1) / float64(s2-s1)
+	if factor <= 0 {
+		return fmt.Errorf("subtitle: blocks %d and %d would swap their order", n, m)
+	}
+	return this.retimeBlocks(0, len(this.subtitleBlock)-1, func(t time.Duration) time.Duration {
+		return t1 + time.Duration(float64(t-s1)*factor)
+	})
+}
+
+// retimeBlocks applies a function to the start and end times of the
+// blocks first to last. The blocks with an invalid time mark are not changed
+func (this *SubtitleSRT) retimeBlocks(first, last int, retime func(time.Duration) time.Duration) error {
+	if first < 0 || last >= len(this.subtitleBlock) || first > last {
+		return fmt.Errorf("subtitle: invalid block range %d to %d", first, last)
+	}
+	for b := first; b <= last; b++ {
+		start, end, err := this.subtitleBlock[b].Times()
+		if err != nil {
+			continue
+		}
+		this.subtitleBlock[b].SetTimes(retime(start), retime(end))
+	}
+	return nil
+}
+
+// RetimeOptions are the timing rules enforced by EnforceTiming
+// The zero value of a threshold disables its rule
+type RetimeOptions struct {
+	// FrameRate is the frames per second of the gaps (0 means the default, 25)
+	FrameRate float64
+	// MinGapFrames is the min gap between two blocks, the block ends earlier
+	MinGapFrames int
+	// ChainFrames closes the gaps shorter than it to MinGapFrames,
+	// the block ends later
+	ChainFrames int
+	// MinDuration and MaxDuration are the min and max times a block is displayed
+	MinDuration time.Duration
+	MaxDuration time.Duration
+}
+
+// Default values of RetimeOptions
+const (
+	defaultFrameRate = 25
+)
+
+// frameRate returns FrameRate or its default
+func (o RetimeOptions) frameRate() float64 {
+	if o.FrameRate <= 0 {
+		return defaultFrameRate
+	}
+	return o.FrameRate
+}
+
+// frames returns the time of n frames
+func (o RetimeOptions) frames(n int) time.Duration {
+	return time.Duration(float64(n) * float64(time.Second) / o.frameRate())
+}
+
+// EnforceTiming changes the end time of the blocks to follow the options,
+// and returns the blocks adjusted. The gap to the next block has priority
+// over the min duration, and the max duration over the chaining
+// The blocks that still overlap the next one are returned as BlockOverlap,
+// NormalizeBlocks sorts or trims them
+// The blocks with an invalid time mark are not changed
+func (this *SubtitleSRT) EnforceTiming(opts RetimeOptions) []BlockChange {
+	defer this.beginEdit("EnforceTiming", opts)(nil)
+	var changes []BlockChange
+	minGap := opts.frames(opts.MinGapFrames)
+	chain := opts.frames(opts.ChainFrames)
+	for b := range this.subtitleBlock {
+		start, end, err := this.subtitleBlock[b].Times()
+		if err != nil {
+			continue
+		}
+		newEnd := end
+		if opts.MaxDuration > 0 && newEnd-start > opts.MaxDuration {
+			newEnd = start + opts.MaxDuration
+		}
+		if opts.MinDuration > 0 && newEnd-start < opts.MinDuration {
+			newEnd = start + opts.MinDuration
+		}
+		overlaps := false
+		if b+1 < len(this.subtitleBlock) {
+			if next, _, err := this.subtitleBlock[b+1].Times(); err == nil {
+				gap := next - newEnd
+				chained := next - minGap
+				tooLong := opts.MaxDuration > 0 && chained-start > opts.MaxDuration
+				switch {
+				case next < start:
+					// The blocks are not sorted, nothing to chain
+				case gap < minGap && chained > start:
+					newEnd = chained
+				case gap < chain && gap > minGap && !tooLong:
+					newEnd = chained
+				}
+				overlaps = newEnd > next
+			}
+		}
+		if newEnd != end {
+			kind := BlockTrimmed
+			if newEnd > end {
+				kind = BlockExtended
+			}
+			old := this.subtitleBlock[b].Timemark
+			this.subtitleBlock[b].SetTimes(start, newEnd)
+			changes = append(changes, BlockChange{kind, b, old, this.subtitleBlock[b].Timemark})
+		}
+		// The next block starts too early to end this one before it
+		if overlaps {
+			timemark := this.subtitleBlock[b].Timemark
+			changes = append(changes, BlockChange{BlockOverlap, b, timemark, timemark})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,140 @@
+package subtitle
+
+import (
+	"bytes"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+// timemarksOf returns the time marks of all the blocks
+func timemarksOf(subt *SubtitleSRT) string {
+	var tms []string
+	for _, sbt := range subt.GetSubtitleBlocks() {
+		tms = append(tms, sbt.Timemark)
+	}
+	return strings.Join(tms, "|")
+}
+
+func TestShiftBlocks(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, StatisticalAlignment)
+
+	if err := subt.ShiftBlockRange(4, 5, 1500*time.Millisecond); err != nil {
+		t.Fatalf("ShiftBlockRange(): %v", err)
+	}
+	blocks := subt.GetSubtitleBlocks()
+	if blocks[3].Timemark != "00:00:07,500 --> 00:00:08,000" || blocks[5].Timemark != "00:00:13,000 --> 00:00:14,500" {
+		t.Fatalf("ShiftBlockRange(): unexpected %s", timemarksOf(&subt))
+	}
+	subt.ShiftBlocks(-2 * time.Second)
+	if blocks := subt.GetSubtitleBlocks(); blocks[0].Timemark != "00:00:00,000 --> 00:00:01,000" {
+		t.Fatalf("ShiftBlocks(): unexpected %s", timemarksOf(&subt))
+	}
+	if err := subt.ShiftBlockRange(5, 6, time.Second); err == nil {
+		t.Fatal("ShiftBlockRange(): want error for an invalid range")
+	}
+
+	// The exporters write the new times
+	var out bytes.Buffer
+	subt.PrintTranslatedSRT(&out)
+	if !strings.Contains(out.String(), "00:00:11,000 --> 00:00:12,500") {
+		t.Fatalf("PrintTranslatedSRT(): times not shifted\n%s", out.String())
+	}
+}
+
+func TestScaleTimes(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, StatisticalAlignment)
+
+	// 25 fps played at 23.976 fps, and back
+	subt.ScaleTimes(FrameRateFactor(25, 23.976))
+	if blocks := subt.GetSubtitleBlocks(); blocks[5].Timemark != "00:00:11,991 --> 00:00:13,555" {
+		t.Fatalf("ScaleTimes(): unexpected %s", timemarksOf(&subt))
+	}
+	subt.ScaleTimes(FrameRateFactor(23.976, 25))
+	if blocks := subt.GetSubtitleBlocks(); blocks[5].Timemark != "00:00:11,500 --> 00:00:13,000" {
+		t.Fatalf("ScaleTimes(): unexpected %s", timemarksOf(&subt))
+	}
+	for _, factor := range []float64{0, -1, math.NaN(), math.Inf(1)} {
+		if err := subt.ScaleTimes(factor); err == nil {
+			t.Fatalf("ScaleTimes(%g): want error", factor)
+		}
+		if err := subt.ScaleTimeRange(0, 1, factor); err == nil {
+			t.Fatalf("ScaleTimeRange(%g): want error", factor)
+		}
+	}
+}
+
+func TestRetimeEmptySubtitle(t *testing.T) {
+	var subt SubtitleSRT
+	if err := subt.ShiftBlocks(time.Second); err != nil {
+		t.Fatalf("ShiftBlocks(): %v", err)
+	}
+	if err := subt.ScaleTimes(1.5); err != nil {
+		t.Fatalf("ScaleTimes(): %v", err)
+	}
+	if err := subt.ScaleTimes(math.NaN()); err == nil {
+		t.Fatal("ScaleTimes(NaN): want error")
+	}
+	if err := subt.ApplyAudioSync(AudioSync{Offset: time.Second, Drift: 1}); err != nil {
+		t.Fatalf("ApplyAudioSync(): %v", err)
+	}
+}
+
+func TestSyncByTwoPoints(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, StatisticalAlignment)
+
+	// Block 0 starts at 1s and block 5 at 11.5s: twice as slow and 10s later
+	if err := subt.SyncByTwoPoints(0, 11*time.Second, 5, 32*time.Second); err != nil {
+		t.Fatalf("SyncByTwoPoints(): %v", err)
+	}
+	blocks := subt.GetSubtitleBlocks()
+	if blocks[0].Timemark != "00:00:11,000 --> 00:00:15,000" || blocks[5].Timemark != "00:00:32,000 --> 00:00:35,000" {
+		t.Fatalf("SyncByTwoPoints(): unexpected %s", timemarksOf(&subt))
+	}
+	if err := subt.SyncByTwoPoints(0, 0, 0, time.Second); err == nil {
+		t.Fatal("SyncByTwoPoints(): want error for the same block")
+	}
+	if err := subt.SyncByTwoPoints(0, 2*time.Second, 5, time.Second); err == nil {
+		t.Fatal("SyncByTwoPoints(): want error when the blocks swap")
+	}
+}
+
+func TestEnforceTiming(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, StatisticalAlignment)
+
+	// 2 frames of min gap and gaps shorter than 15 frames chained, at 25 fps
+	changes := subt.EnforceTiming(RetimeOptions{
+		FrameRate:    25,
+		MinGapFrames: 2,
+		ChainFrames:  15,
+		MinDuration:  time.Second,
+		MaxDuration:  2 * time.Second,
+	})
+	want := "00:00:01,000 --> 00:00:03,000|00:00:03,500 --> 00:00:05,500|00:00:06,500 --> 00:00:07,420|" +
+		"00:00:07,500 --> 00:00:08,420|00:00:08,500 --> 00:00:10,500|00:00:11,500 --> 00:00:13,000"
+	if have := timemarksOf(&subt); have != want {
+		t.Fatalf("EnforceTiming(): want %s have %s", want, have)
+	}
+	if len(changes) != 4 || changes[0].Kind != BlockTrimmed || changes[1].Kind != BlockExtended || changes[1].Index != 2 {
+		t.Fatalf("EnforceTiming(): unexpected changes %v", changes)
+	}
+	if changes := subt.EnforceTiming(RetimeOptions{MinGapFrames: 2, ChainFrames: 15}); len(changes) != 1 || changes[0].Index != 0 {
+		t.Fatalf("EnforceTiming(): unexpected changes %v", changes)
+	}
+
+	// Block 2 starts with block 1, and cannot end before it
+	var overlap SubtitleSRT
+	overlap.SetOriginalSrt(strings.NewReader(`1
+00:00:01,000 --> 00:00:03,000
+One.
+
+2
+00:00:01,000 --> 00:00:04,000
+Two.
+`))
+	changes = overlap.EnforceTiming(RetimeOptions{MinGapFrames: 2})
+	if len(changes) != 1 || changes[0].Kind != BlockOverlap || changes[0].Index != 0 {
+		t.Fatalf("EnforceTiming(): unexpected changes %v", changes)
+	}
+}
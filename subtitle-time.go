@@ -0,0 +1,115 @@
+package subtitle
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// -------------------------------------
+// Timing of the subtitle blocks
+// -------------------------------------
+
+// The time mark of a block, the text after the end time (coordinates) is kept
+var timemarkRegexp = regexp.MustCompile(`^\s*(\d+):(\d{1,2}):(\d{1,2})[,.](\d{1,3})\s*-->\s*(\d+):(\d{1,2}):(\d{1,2})[,.](\d{1,3})(.*)$`)
+
+// ParseTimemark returns the start and end times of a time mark
+// (hh:mm:ss,mmm --> hh:mm:ss,mmm)
+func ParseTimemark(tm string) (time.Duration, time.Duration, error) {
+	m := timemarkRegexp.FindStringSubmatch(tm)
+	if m == nil {
+		return 0, 0, fmt.Errorf("subtitle: invalid time mark %q", tm)
+	}
+	return parseTime(m[1:5]), parseTime(m[5:9]), nil
+}
+
+// parseTime returns the time of hours, minutes, seconds and milliseconds
+func parseTime(parts []string) time.Duration {
+	var n [4]int
+	for i, p := range parts {
+		n[i], _ = strconv.Atoi(p)
+	}
+	// Milliseconds with less than 3 digits are fractions of a second
+	for l := len(parts[3]); l < 3; l++ {
+		n[3] *= 10
+	}
+	return time.Duration(n[0])*time.Hour + time.Duration(n[1])*time.Minute +
+		time.Duration(n[2])*time.Second + time.Duration(n[3])*time.Millisecond
+}
+
+// FormatTimemark returns the time mark of the start and end times
+// Negative times are written as 0
+func FormatTimemark(start, end time.Duration) string {
+	return formatTime(start) + " --> " + formatTime(end)
+}
+
+// formatTime returns a time as hh:mm:ss,mmm
+func formatTime(t time.Duration) string {
+	if t < 0 {
+		t = 0
+	}
+	t = t.Round(time.Millisecond)
+	h := t / time.Hour
+	m := (t % time.Hour) / time.Minute
+	s := (t % time.Minute) / time.Second
+	ms := (t % time.Second) / time.Millisecond
+	return fmt.Sprintf("%02d:%02d:%02d,%03d", h, m, s, ms)
+}
+
+// Times returns the start and end times of the block
+func (this *SubtitleBlock) Times() (time.Duration, time.Duration, error) {
+	return ParseTimemark(this.Timemark)
+}
+
+// SetTimes sets the start and end times of the block
+// The text after the time mark (coordinates), if any, is kept
+func (this *SubtitleBlock) SetTimes(start, end time.Duration) {
+	rest := ""
+	if m := timemarkRegexp.FindStringSubmatch(this.Timemark); m != nil {
+		rest = m[9]
+	}
+	this.Timemark = FormatTimemark(start, end) + rest
+}
+
+// Duration returns the time the block is displayed,
+// 0 if the time mark is not valid
+func (this *SubtitleBlock) Duration() time.Duration {
+	start, end, err := this.Times()
+	if err != nil {
+		return 0
+	}
+	return end - start
+}
+
+// blockLines returns the first and last lines of a subtitle block
+func (this *SubtitleSRT) blockLines(theBlock int) (int, int) {
+	init := 0
+	for _, sbt := range this.subtitleBlock[:theBlock] {
+		init += sbt.Nlines
+	}
+	return init, init + this.subtitleBlock[theBlock].Nlines - 1
+}
+
+// blockOfLines returns the subtitle block of each line
+func (this *SubtitleSRT) blockOfLines() []int {
+	blocks := make([]int, 0, len(this.originalLine))
+	for b, sbt := range this.subtitleBlock {
+		for i := 0; i < sbt.Nlines; i++ {
+			blocks = append(blocks, b)
+		}
+	}
+	return blocks
+}
+
+// translatedLinesOf returns the n translated lines from the line init,
+// the lines not translated yet are empty
+func (this *SubtitleSRT) translatedLinesOf(init, n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		if init+i < len(this.translatedLine) {
+			lines[i] = this.translatedLine[init+i]
+		}
+	}
+	return lines
+}
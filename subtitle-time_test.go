@@ -0,0 +1,30 @@
+package subtitle
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimemark(t *testing.T) {
+	start, end, err := ParseTimemark("01:02:03,045 --> 01:02:04.5 X1:10 X2:20")
+	if err != nil {
+		t.Fatalf("ParseTimemark(): %v", err)
+	}
+	if start != time.Hour+2*time.Minute+3*time.Second+45*time.Millisecond || end-start != 1455*time.Millisecond {
+		t.Fatalf("ParseTimemark(): unexpected times %v %v", start, end)
+	}
+	if _, _, err := ParseTimemark("01:02:03 -> 01:02:04"); err == nil {
+		t.Fatal("ParseTimemark(): want error for an invalid time mark")
+	}
+}
+
+func TestSetTimes(t *testing.T) {
+	sbt := SubtitleBlock{"1", "00:00:01,000 --> 00:00:02,000 X1:10", 1}
+	sbt.SetTimes(90*time.Minute+500*time.Millisecond, -time.Second)
+	if sbt.Timemark != "01:30:00,500 --> 00:00:00,000 X1:10" {
+		t.Fatalf("SetTimes(): unexpected time mark %q", sbt.Timemark)
+	}
+	if sbt.Duration() >= 0 {
+		t.Fatalf("Duration(): want negative, have %v", sbt.Duration())
+	}
+}
@@ -11,6 +11,8 @@ import (
 // Translate() translates the original text in originalLine
 // into the requested language.
 // Then, it stores the translatedText and splits line sets and translatedLine
+// The locked LineSets keep their translation. It returns the length of the
+// translated text, or 0 if it is not stored
 func (this *SubtitleSRT) Translate(targetLang string, projectID string, model string) int {
 	// Verify that data is already loaded
 	if !this.IsLoadedSRT() {
@@ -50,23 +52,81 @@ func (this *SubtitleSRT) Translate(targetLang string, projectID string, model st
 		}
 	*/
 
-	txt, _ := this.GetOriginalText()
+	// The locked LineSets keep their translation: only the original text
+	// between them is translated
+	segments, locked := this.translationSegments()
+	var contents []string
+	for _, seg := range segments {
+		if seg != "" {
+			contents = append(contents, seg)
+		}
+	}
+	sourceLang := this.metadata.SourceLanguage
+	if sourceLang == "" {
+		sourceLang = "en"
+	}
+
+	var translations []*translatepb.Translation
+	if len(contents) > 0 {
+		req := &translatepb.TranslateTextRequest{
+			Contents:           contents,
+			MimeType:           "text/plain",
+			SourceLanguageCode: sourceLang,
+			TargetLanguageCode: targetLang,
+			Parent:             fmt.Sprintf("projects/%s", projectID),
+			Model:              fmt.Sprintf("projects/%s/locations/global/models/general/%s", projectID, model),
+		}
 
-	req := &translatepb.TranslateTextRequest{
-		Contents:           []string{txt},
-		MimeType:           "text/plain",
-		SourceLanguageCode: "en",
-		TargetLanguageCode: targetLang,
-		Parent:             fmt.Sprintf("projects/%s", projectID),
-		Model:              fmt.Sprintf("projects/%s/locations/global/models/general/%s", projectID, model),
+		resp, err := client.TranslateText(ctx, req)
+		check(err)
+		translations = resp.GetTranslations()
 	}
 
-	resp, err := client.TranslateText(ctx, req)
-	check(err)
+	// Put the translations of the locked LineSets back between the translated texts
+	var parts []string
+	for k, seg := range segments {
+		if seg != "" {
+			parts = append(parts, translations[0].GetTranslatedText())
+			translations = translations[1:]
+		}
+		if k < len(locked) {
+			parts = append(parts, locked[k])
+		}
+	}
 
-	// Store the translatedText
-	this.SetTranslatedText(resp.GetTranslations()[0].GetTranslatedText())
+	// Store the language and the translatedText
+	this.SetTargetLanguage(targetLang)
+	if err := this.SetTranslatedTextWithOptions(joinStrings(parts...), AlignOptions{}); err != nil {
+		return 0
+	}
 
-	return len([]rune(this.translatedText))
+	return countGraphemes(this.translatedText)
 
 }
+
+// translationSegments returns the original text of the lines between
+// the locked LineSets, and the translations of the locked LineSets
+// There is one more segment than locked LineSets, a segment can be empty
+func (this *SubtitleSRT) translationSegments() ([]string, []string) {
+	var segments, locked []string
+	line := 0
+	for i, ls := range this.lineSet {
+		if !ls.Locked {
+			continue
+		}
+		segments = append(segments, this.originalTextOfLines(line, ls.InitLine-1))
+		locked = append(locked, this.translatedSet[i])
+		line = ls.LastLine + 1
+	}
+	segments = append(segments, this.originalTextOfLines(line, len(this.originalLine)-1))
+	return segments, locked
+}
+
+// originalTextOfLines returns the original text of the lines init to last,
+// as GetOriginalText
+func (this *SubtitleSRT) originalTextOfLines(init, last int) string {
+	if init > last {
+		return ""
+	}
+	return this.normalizeOriginal(JoinAllLines(this.originalLine[init : last+1]...))
+}
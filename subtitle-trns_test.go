@@ -58,3 +58,19 @@ func TestTranslateOrigText(t *testing.T) {
 		t.Fatalf("Translation failed: want %q have %q.", want, subt.translatedLine)
 	}
 }
+
+func TestTranslationSegments(t *testing.T) {
+	subt := newTestSubtitle(alignTestSrt, alignTestTxt, ExactMatchAlignment)
+	if segments, locked := subt.translationSegments(); len(segments) != 1 || len(locked) != 0 {
+		t.Fatalf("translationSegments(): unexpected %q %q", segments, locked)
+	}
+
+	// The text of the locked LineSet is not translated again
+	subt.LockLineSet(1)
+	segments, locked := subt.translationSegments()
+	original, _ := subt.GetOriginalTextOfLineSet(1)
+	if len(segments) != 2 || len(locked) != 1 || locked[0] != subt.translatedSet[1] ||
+		strings.Contains(segments[0], original) || strings.Contains(segments[1], original) {
+		t.Fatalf("translationSegments(): unexpected %q %q", segments, locked)
+	}
+}
@@ -18,10 +18,16 @@ type SubtitleBlock struct {
 
 // A LineSet is a set of lines within the list of subtitle text lines
 // Each LineSet is process as a block to match original and translation linebreaks
+// Exact is true when the LineSet was detected by an exact match of its lines
+// Status, Locked and Comments are the state of its review
 // (****) This may be simplified if LastLine is not included (as in an array)
 type LineSet struct {
 	InitLine int
 	LastLine int
+	Exact    bool
+	Status   LineSetStatus
+	Locked   bool
+	Comments []Comment `json:",omitempty"`
 }
 
 // A subtitle file contains:
@@ -30,6 +36,12 @@ type LineSet struct {
 //   * an array of lines in the translated language
 //   * an array of line sets definition
 //   * an array of the translated text of the LineSet:s
+//   * the strategy and options used to split the translated text into LineSet:s
+//   * the splitter and the constraints of the translated lines
+//   * the Normalizers of the original and the translation
+//   * the handling of RTL output
+//   * the metadata of the project, with the languages of the original and the translation
+//   * the undo and redo history of the edits, and their journal
 //
 // SubtitleSRT is based in the SRT definition, each subtitle block consists of
 //   <order>     === (\d{1,n})
@@ -43,4 +55,16 @@ type SubtitleSRT struct {
 	translatedLine []string
 	translatedSet  []string
 	translatedText string
+	alignStrategy  AlignStrategy
+	alignOptions   AlignOptions
+	splitOptions   SplitOptions
+	lineSplitter   LineSplitter
+	rtlOptions     RTLOptions
+	metadata       Metadata
+
+	originalNormalizer   Normalizer
+	translatedNormalizer Normalizer
+
+	history editHistory
+	journal *journalWriter
 }
@@ -0,0 +1,263 @@
+package subtitle
+
+import (
+	"unicode"
+	"unicode/utf8"
+)
+
+// ---------------------------------------------------------------
+// Extended grapheme clusters (Unicode UAX #29): the user-perceived
+// characters used to count chars and measure the width of the lines
+// ---------------------------------------------------------------
+
+// graphemeBreak is the Grapheme_Cluster_Break property of a rune
+type graphemeBreak int
+
+const (
+	gbOther graphemeBreak = iota
+	gbCR
+	gbLF
+	gbControl
+	gbExtend
+	gbZWJ
+	gbRegionalIndicator
+	gbPrepend
+	gbSpacingMark
+	gbL
+	gbV
+	gbT
+	gbLV
+	gbLVT
+)
+
+// Ranges of the properties that are not a general category
+var (
+	gbExtendTable = &unicode.RangeTable{
+		R16: []unicode.Range16{
+			{0x09BE, 0x09BE, 1}, {0x09D7, 0x09D7, 1}, {0x0B3E, 0x0B3E, 1},
+			{0x0B57, 0x0B57, 1}, {0x0BBE, 0x0BBE, 1}, {0x0BD7, 0x0BD7, 1},
+			{0x0CC2, 0x0CC2, 1}, {0x0CD5, 0x0CD6, 1}, {0x0D3E, 0x0D3E, 1},
+			{0x0D57, 0x0D57, 1}, {0x0DCF, 0x0DCF, 1}, {0x0DDF, 0x0DDF, 1},
+			{0x200C, 0x200C, 1}, {0x302E, 0x302F, 1}, {0xFF9E, 0xFF9F, 1},
+		},
+		R32: []unicode.Range32{
+			{0x1D165, 0x1D165, 1}, {0x1D16E, 0x1D172, 1},
+			{0x1F3FB, 0x1F3FF, 1}, {0xE0020, 0xE007F, 1},
+		},
+	}
+	gbPrependTable = &unicode.RangeTable{
+		R16: []unicode.Range16{
+			{0x0600, 0x0605, 1}, {0x06DD, 0x06DD, 1}, {0x070F, 0x070F, 1},
+			{0x0890, 0x0891, 1}, {0x08E2, 0x08E2, 1}, {0x0D4E, 0x0D4E, 1},
+		},
+		R32: []unicode.Range32{
+			{0x110BD, 0x110BD, 1}, {0x110CD, 0x110CD, 1}, {0x111C2, 0x111C3, 1},
+			{0x1193F, 0x1193F, 1}, {0x11941, 0x11941, 1}, {0x11A3A, 0x11A3A, 1},
+			{0x11A84, 0x11A89, 1}, {0x11D46, 0x11D46, 1}, {0x11F02, 0x11F02, 1},
+		},
+	}
+	extendedPictographicTable = &unicode.RangeTable{
+		R16: []unicode.Range16{
+			{0x00A9, 0x00A9, 1}, {0x00AE, 0x00AE, 1}, {0x203C, 0x203C, 1},
+			{0x2049, 0x2049, 1}, {0x2122, 0x2122, 1}, {0x2139, 0x2139, 1},
+			{0x2194, 0x2199, 1}, {0x21A9, 0x21AA, 1}, {0x231A, 0x231B, 1},
+			{0x2328, 0x2328, 1}, {0x2388, 0x2388, 1}, {0x23CF, 0x23CF, 1},
+			{0x23E9, 0x23F3, 1}, {0x23F8, 0x23FA, 1}, {0x24C2, 0x24C2, 1},
+			{0x25AA, 0x25AB, 1}, {0x25B6, 0x25B6, 1}, {0x25C0, 0x25C0, 1},
+			{0x25FB, 0x25FE, 1}, {0x2600, 0x27BF, 1}, {0x2934, 0x2935, 1},
+			{0x2B05, 0x2B07, 1}, {0x2B1B, 0x2B1C, 1}, {0x2B50, 0x2B50, 1},
+			{0x2B55, 0x2B55, 1}, {0x3030, 0x3030, 1}, {0x303D, 0x303D, 1},
+			{0x3297, 0x3297, 1}, {0x3299, 0x3299, 1},
+		},
+		R32: []unicode.Range32{
+			{0x1F000, 0x1F0FF, 1}, {0x1F10D, 0x1F10F, 1}, {0x1F12F, 0x1F12F, 1},
+			{0x1F16C, 0x1F171, 1}, {0x1F17E, 0x1F17F, 1}, {0x1F18E, 0x1F18E, 1},
+			{0x1F191, 0x1F19A, 1}, {0x1F1AD, 0x1F1E5, 1}, {0x1F201, 0x1F20F, 1},
+			{0x1F21A, 0x1F21A, 1}, {0x1F22F, 0x1F22F, 1}, {0x1F232, 0x1F23A, 1},
+			{0x1F23C, 0x1F23F, 1}, {0x1F249, 0x1F3FA, 1}, {0x1F400, 0x1F53D, 1},
+			{0x1F546, 0x1F64F, 1}, {0x1F680, 0x1F6FF, 1}, {0x1F774, 0x1F77F, 1},
+			{0x1F7D5, 0x1F7FF, 1}, {0x1F80C, 0x1F80F, 1}, {0x1F848, 0x1F84F, 1},
+			{0x1F85A, 0x1F85F, 1}, {0x1F888, 0x1F88F, 1}, {0x1F8AE, 0x1F8FF, 1},
+			{0x1F90C, 0x1F93A, 1}, {0x1F93C, 0x1F945, 1}, {0x1F947, 0x1FAFF, 1},
+			{0x1FC00, 0x1FFFD, 1},
+		},
+	}
+	// Indic conjuncts (Indic_Conjunct_Break): consonants and viramas
+	// of Devanagari, Bengali, Gujarati, Oriya, Telugu and Malayalam
+	incbConsonantTable = &unicode.RangeTable{
+		R16: []unicode.Range16{
+			{0x0915, 0x0939, 1}, {0x0958, 0x095F, 1}, {0x0978, 0x097F, 1},
+			{0x0995, 0x09A8, 1}, {0x09AA, 0x09B0, 1}, {0x09B2, 0x09B2, 1},
+			{0x09B6, 0x09B9, 1}, {0x09DC, 0x09DD, 1}, {0x09DF, 0x09DF, 1},
+			{0x09F0, 0x09F1, 1}, {0x0A95, 0x0AA8, 1}, {0x0AAA, 0x0AB0, 1},
+			{0x0AB2, 0x0AB3, 1}, {0x0AB5, 0x0AB9, 1}, {0x0AF9, 0x0AF9, 1},
+			{0x0B15, 0x0B28, 1}, {0x0B2A, 0x0B30, 1}, {0x0B32, 0x0B33, 1},
+			{0x0B35, 0x0B39, 1}, {0x0B5C, 0x0B5D, 1}, {0x0B5F, 0x0B5F, 1},
+			{0x0B71, 0x0B71, 1}, {0x0C15, 0x0C28, 1}, {0x0C2A, 0x0C39, 1},
+			{0x0C58, 0x0C5A, 1}, {0x0D15, 0x0D3A, 1},
+		},
+	}
+	incbLinkerTable = &unicode.RangeTable{
+		R16: []unicode.Range16{
+			{0x094D, 0x094D, 1}, {0x09CD, 0x09CD, 1}, {0x0ACD, 0x0ACD, 1},
+			{0x0B4D, 0x0B4D, 1}, {0x0C4D, 0x0C4D, 1}, {0x0D4D, 0x0D4D, 1},
+		},
+	}
+)
+
+// graphemeBreakOf returns the Grapheme_Cluster_Break property of a rune
+func graphemeBreakOf(r rune) graphemeBreak {
+	switch {
+	case r == '\r':
+		return gbCR
+	case r == '\n':
+		return gbLF
+	case r == 0x200D:
+		return gbZWJ
+	case r >= 0x1F1E6 && r <= 0x1F1FF:
+		return gbRegionalIndicator
+	case unicode.Is(gbPrependTable, r):
+		return gbPrepend
+	case unicode.In(r, unicode.Mn, unicode.Me, gbExtendTable):
+		return gbExtend
+	case unicode.In(r, unicode.Cc, unicode.Cf, unicode.Zl, unicode.Zp, unicode.Cs):
+		return gbControl
+	case r == 0x0E33 || r == 0x0EB3 || unicode.Is(unicode.Mc, r):
+		return gbSpacingMark
+	case r >= 0x1100 && r <= 0x115F, r >= 0xA960 && r <= 0xA97C:
+		return gbL
+	case r >= 0x1160 && r <= 0x11A7, r >= 0xD7B0 && r <= 0xD7C6:
+		return gbV
+	case r >= 0x11A8 && r <= 0x11FF, r >= 0xD7CB && r <= 0xD7FB:
+		return gbT
+	case r >= 0xAC00 && r <= 0xD7A3:
+		if (r-0xAC00)%28 == 0 {
+			return gbLV
+		}
+		return gbLVT
+	}
+	return gbOther
+}
+
+// nextGraphemeCluster returns the length in bytes of the first
+// extended grapheme cluster of s
+func nextGraphemeCluster(s string) int {
+	if s == "" {
+		return 0
+	}
+	r, size := utf8.DecodeRuneInString(s)
+	prev := graphemeBreakOf(r)
+	pos := size
+
+	// State of the rules that look back further than the previous rune
+	pictographic := unicode.Is(extendedPictographicTable, r) // GB11: ExtPict Extend*
+	pictographicZWJ := false                                 // GB11: ExtPict Extend* ZWJ
+	regionalIndicators := 0                                  // GB12, GB13
+	if prev == gbRegionalIndicator {
+		regionalIndicators = 1
+	}
+	conjunct := unicode.Is(incbConsonantTable, r) // GB9c: Consonant [Extend Linker]*
+	conjunctLinker := false                       // GB9c: ... with a Linker
+
+	for pos < len(s) {
+		r, size = utf8.DecodeRuneInString(s[pos:])
+		next := graphemeBreakOf(r)
+		join := false
+		switch {
+		case prev == gbCR && next == gbLF: // GB3
+			join = true
+		case prev == gbCR, prev == gbLF, prev == gbControl: // GB4
+		case next == gbCR, next == gbLF, next == gbControl: // GB5
+		case prev == gbL && (next == gbL || next == gbV || next == gbLV || next == gbLVT): // GB6
+			join = true
+		case (prev == gbLV || prev == gbV) && (next == gbV || next == gbT): // GB7
+			join = true
+		case (prev == gbLVT || prev == gbT) && next == gbT: // GB8
+			join = true
+		case next == gbExtend, next == gbZWJ, next == gbSpacingMark, prev == gbPrepend: // GB9, GB9a, GB9b
+			join = true
+		case conjunctLinker && unicode.Is(incbConsonantTable, r): // GB9c
+			join = true
+		case pictographicZWJ && unicode.Is(extendedPictographicTable, r): // GB11
+			join = true
+		case prev == gbRegionalIndicator && next == gbRegionalIndicator && regionalIndicators%2 == 1: // GB12, GB13
+			join = true
+		}
+		if !join {
+			break
+		}
+
+		// Update the state with the rune joined
+		switch {
+		case unicode.Is(extendedPictographicTable, r):
+			pictographic, pictographicZWJ = true, false
+		case next == gbExtend && pictographic && !pictographicZWJ:
+		case next == gbZWJ && pictographic && !pictographicZWJ:
+			pictographicZWJ = true
+		default:
+			pictographic, pictographicZWJ = false, false
+		}
+		switch {
+		case unicode.Is(incbConsonantTable, r):
+			conjunct, conjunctLinker = true, false
+		case conjunct && unicode.Is(incbLinkerTable, r):
+			conjunctLinker = true
+		case conjunct && (next == gbExtend || next == gbZWJ):
+		default:
+			conjunct, conjunctLinker = false, false
+		}
+		if next == gbRegionalIndicator {
+			regionalIndicators++
+		}
+		prev = next
+		pos += size
+	}
+	return pos
+}
+
+// graphemeClusters splits a text into its extended grapheme clusters
+func graphemeClusters(s string) []string {
+	var clusters []string
+	for s != "" {
+		n := nextGraphemeCluster(s)
+		clusters = append(clusters, s[:n])
+		s = s[n:]
+	}
+	return clusters
+}
+
+// countGraphemes returns the number of extended grapheme clusters of a text
+func countGraphemes(s string) int {
+	n := 0
+	for s != "" {
+		s = s[nextGraphemeCluster(s):]
+		n++
+	}
+	return n
+}
+
+// graphemePrefix returns the first n extended grapheme clusters of a text,
+// or the whole text if it has less than n
+func graphemePrefix(s string, n int) string {
+	pos := 0
+	for i := 0; i < n && pos < len(s); i++ {
+		pos += nextGraphemeCluster(s[pos:])
+	}
+	return s[:pos]
+}
+
+// graphemeWidth returns the width of a grapheme cluster in a monospace
+// display: the widest of its runes, 2 for emoji sequences and flags
+func graphemeWidth(cluster string) int {
+	width := 0
+	for _, r := range cluster {
+		switch {
+		case r == 0xFE0F, r == 0x200D, r >= 0x1F1E6 && r <= 0x1F1FF:
+			// Emoji presentation, ZWJ sequence or flag
+			return 2
+		case runeWidth(r) > width:
+			width = runeWidth(r)
+		}
+	}
+	return width
+}
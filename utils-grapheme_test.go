@@ -0,0 +1,135 @@
+package subtitle
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestGraphemeClusters(t *testing.T) {
+	for _, tc := range []struct {
+		text string
+		want []string
+	}{
+		{"cafe\u0301!", []string{"c", "a", "f", "e\u0301", "!"}},
+		{"a\r\nb", []string{"a", "\r\n", "b"}},
+		{"👨‍👩‍👧 👍🏽", []string{"👨‍👩‍👧", " ", "👍🏽"}},
+		{"🇪🇸🇫🇷🇮", []string{"🇪🇸", "🇫🇷", "🇮"}},
+		{"नमस्ते", []string{"न", "म", "स्ते"}},
+		{"한한", []string{"한", "한"}},
+	} {
+		have := graphemeClusters(tc.text)
+		if strings.Join(have, "|") != strings.Join(tc.want, "|") {
+			t.Fatalf("graphemeClusters(%q): want %q have %q", tc.text, tc.want, have)
+		}
+		if countGraphemes(tc.text) != len(tc.want) {
+			t.Fatalf("countGraphemes(%q): want %d have %d", tc.text, len(tc.want), countGraphemes(tc.text))
+		}
+	}
+}
+
+// Rows of GraphemeBreakTest.txt (Unicode 15.1): the code points of a text,
+// ÷ where it breaks and × where it does not
+var graphemeBreakTests = []string{
+	// GB3, GB4, GB5, GB999
+	"÷ 0020 ÷ 0020 ÷",
+	"÷ 000D × 000A ÷",
+	"÷ 000A ÷ 000D ÷",
+	"÷ 0001 ÷ 0308 ÷",
+	"÷ 0600 ÷ 000A ÷",
+	// GB9, GB9a, GB9b
+	"÷ 0020 × 0308 ÷ 0020 ÷",
+	"÷ 0061 × 200D ÷ 0062 ÷",
+	"÷ 0020 × 0903 ÷",
+	"÷ 0600 × 0020 ÷",
+	"÷ 0600 × 0308 ÷",
+	// GB6, GB7, GB8: Hangul
+	"÷ 1100 × 1100 ÷",
+	"÷ 1100 × 1160 ÷",
+	"÷ 1100 × AC00 ÷",
+	"÷ 1100 × AC01 ÷",
+	"÷ 1160 × 1160 ÷",
+	"÷ 1160 × 11A8 ÷",
+	"÷ AC00 × 1160 ÷",
+	"÷ AC00 × 11A8 ÷",
+	"÷ AC01 × 11A8 ÷",
+	"÷ AC01 ÷ 1160 ÷",
+	"÷ 11A8 ÷ 1100 ÷",
+	"÷ AC00 ÷ AC00 ÷",
+	// GB9c: Indic conjuncts
+	"÷ 0915 × 094D × 0924 ÷",
+	"÷ 0915 × 094D × 094D × 0924 ÷",
+	"÷ 0915 × 094D × 200D × 0924 ÷",
+	"÷ 0915 × 093C × 200D × 094D × 0924 ÷",
+	"÷ 0915 × 093C × 094D × 0924 ÷",
+	"÷ 0915 × 094D × 0924 × 094D × 092F ÷",
+	"÷ 0915 × 094D ÷ 0061 ÷",
+	"÷ 0061 × 094D ÷ 0924 ÷",
+	"÷ 003F × 094D ÷ 0924 ÷",
+	"÷ 0915 ÷ 0924 ÷",
+	"÷ 0995 × 09CD × 09B7 ÷",
+	"÷ 0B95 × 0BCD ÷ 0BB7 ÷",
+	// GB11: emoji ZWJ sequences
+	"÷ 1F476 × 1F3FF ÷ 1F476 ÷",
+	"÷ 1F6D1 × 200D × 1F6D1 ÷",
+	"÷ 0061 × 200D ÷ 1F6D1 ÷",
+	"÷ 2701 × 200D × 2701 ÷",
+	"÷ 0061 × 200D ÷ 2701 ÷",
+	"÷ 1F6D1 × 200D ÷ 0061 ÷",
+	"÷ 1F6D1 × 0308 × 0308 × 200D × 1F6D1 ÷",
+	"÷ 1F476 × 1F3FF × 0308 × 200D × 1F476 × 1F3FF ÷",
+	// GB12, GB13: regional indicators
+	"÷ 1F1E6 × 1F1E7 ÷ 1F1E8 ÷ 0062 ÷",
+	"÷ 0061 ÷ 1F1E6 × 1F1E7 ÷ 1F1E8 ÷ 0062 ÷",
+	"÷ 0061 ÷ 1F1E6 × 1F1E7 × 200D ÷ 1F1E8 ÷ 0062 ÷",
+	"÷ 0061 ÷ 1F1E6 × 200D ÷ 1F1E7 × 1F1E8 ÷ 0062 ÷",
+	"÷ 0061 ÷ 1F1E6 × 1F1E7 ÷ 1F1E8 × 1F1E9 ÷ 0062 ÷",
+}
+
+func TestGraphemeBreakTest(t *testing.T) {
+	for _, row := range graphemeBreakTests {
+		var text strings.Builder
+		var want []string
+		cluster := ""
+		for _, field := range strings.Fields(row) {
+			switch field {
+			case "÷":
+				if cluster != "" {
+					want = append(want, cluster)
+				}
+				cluster = ""
+			case "×":
+			default:
+				code, err := strconv.ParseUint(field, 16, 32)
+				if err != nil {
+					t.Fatalf("%s: %v", row, err)
+				}
+				cluster += string(rune(code))
+				text.WriteRune(rune(code))
+			}
+		}
+		have := graphemeClusters(text.String())
+		if strings.Join(have, "|") != strings.Join(want, "|") {
+			t.Fatalf("graphemeClusters(%s): want %+q have %+q", row, want, have)
+		}
+	}
+}
+
+func TestGraphemeWidth(t *testing.T) {
+	for text, want := range map[string]int{
+		"cafe\u0301": 4,
+		"👍🏽 ok":      5,
+		"🇪🇸":         2,
+		"日本":         4,
+	} {
+		if have := lineWidth(text); have != want {
+			t.Fatalf("lineWidth(%q): want %d have %d", text, want, have)
+		}
+	}
+	if have := PrintStringMaxWidth("cafe\u0301 con leche", 8); have != "cafe\u0301 ..." {
+		t.Fatalf("PrintStringMaxWidth(): unexpected %q", have)
+	}
+	if have := PrintStringMaxWidth("cafe\u0301", 6); have != "cafe\u0301  " {
+		t.Fatalf("PrintStringMaxWidth(): unexpected %q", have)
+	}
+}
@@ -0,0 +1,217 @@
+package subtitle
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ----------------------------------------------------------
+// Script-aware segmentation: scripts written without spaces
+// (Chinese, Japanese, Thai) and east asian width
+// ----------------------------------------------------------
+
+// A segment is the smallest piece of text that can be put in a line:
+// a word in space-separated scripts, a character (with the punctuation
+// that cannot be separated from it) in CJK, a cluster in Thai
+type segment struct {
+	text  string
+	space bool // there is a space before the segment
+}
+
+// Kinsoku rules: chars that cannot start a line, and chars that cannot end it
+const (
+	kinsokuNoStart = "、。，．・：；？！ー）」』】〉》〕］｝’”ぁぃぅぇぉっゃゅょゎァィゥェォッャュョヮヵヶ々〻…‥゛゜ヽヾゝゞ〜～!),.:;?]}%"
+	kinsokuNoEnd   = "（「『【〈《〔［｛‘“([{"
+)
+
+// isCJK returns true if the rune is written without spaces in Chinese or Japanese
+func isCJK(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Bopomofo) ||
+		(r >= 0x3000 && r <= 0x303F) || // CJK symbols and punctuation
+		(r >= 0xFF00 && r <= 0xFFEF) // Halfwidth and fullwidth forms
+}
+
+// isThai returns true if the rune is Thai
+func isThai(r rune) bool {
+	return unicode.Is(unicode.Thai, r)
+}
+
+// isThaiLeadingVowel returns true for the Thai vowels written before the consonant
+func isThaiLeadingVowel(r rune) bool {
+	return r >= 0x0E40 && r <= 0x0E44
+}
+
+// isThaiFollowing returns true for the Thai marks and vowels that
+// cannot be separated from the previous char
+func isThaiFollowing(r rune) bool {
+	return r == 0x0E30 || r == 0x0E31 || r == 0x0E32 || r == 0x0E33 ||
+		(r >= 0x0E34 && r <= 0x0E3A) || r == 0x0E45 || (r >= 0x0E47 && r <= 0x0E4E)
+}
+
+// isBreakingSpace returns true if a line can be broken at the rune
+func isBreakingSpace(r rune) bool {
+	return unicode.IsSpace(r) && r != '\u00a0' && r != '\u202f' && r != '\u2007'
+}
+
+// isNoSpaceScript returns true if the rune belongs to a script written without spaces
+func isNoSpaceScript(r rune) bool {
+	return isCJK(r) || isThai(r)
+}
+
+// hasNoSpaceScript returns true if the text has chars of a script written without spaces
+func hasNoSpaceScript(s string) bool {
+	return strings.IndexFunc(s, isNoSpaceScript) >= 0
+}
+
+// segmentText splits a text into segments, the break opportunities are
+// the spaces (but the no-break ones), and between CJK characters or Thai clusters
+func segmentText(text string) []segment {
+	var segs []segment
+	for _, field := range strings.FieldsFunc(text, isBreakingSpace) {
+		for k, unit := range splitNoSpaceScript(field) {
+			segs = append(segs, segment{unit, k == 0 && len(segs) > 0})
+		}
+	}
+	return segs
+}
+
+// splitNoSpaceScript splits a word with no spaces into units
+// Latin runs are kept together, CJK chars and Thai clusters are units
+// by themselves, and kinsoku punctuation sticks to its neighbour
+func splitNoSpaceScript(word string) []string {
+	if !hasNoSpaceScript(word) {
+		return []string{word}
+	}
+	var units []string
+	var cur []rune
+	var prev rune
+	attachNext := false
+	for _, r := range word {
+		startNew := false
+		switch {
+		case len(cur) == 0, attachNext:
+		case strings.ContainsRune(kinsokuNoStart, r), isThaiFollowing(r), unicode.Is(unicode.Mn, r):
+		case isNoSpaceScript(r), isNoSpaceScript(prev):
+			startNew = true
+		}
+		if startNew {
+			units = append(units, string(cur))
+			cur = nil
+		}
+		cur = append(cur, r)
+		attachNext = strings.ContainsRune(kinsokuNoEnd, r) || isThaiLeadingVowel(r)
+		prev = r
+	}
+	if len(cur) > 0 {
+		units = append(units, string(cur))
+	}
+	return units
+}
+
+// joinSegments returns the text of the segments, with the spaces between them
+func joinSegments(segs []segment) string {
+	text := new(strings.Builder)
+	for i, s := range segs {
+		if i > 0 && s.space {
+			text.WriteString(" ")
+		}
+		text.WriteString(s.text)
+	}
+	return text.String()
+}
+
+// splitFirstWords splits a text into its first n words (segments) and the rest
+func splitFirstWords(text string, n int) (string, string) {
+	segs := segmentText(text)
+	if n > len(segs) {
+		n = len(segs)
+	}
+	return joinSegments(segs[:n]), joinSegments(segs[n:])
+}
+
+// splitLastWords splits a text into the rest and its last n words (segments)
+func splitLastWords(text string, n int) (string, string) {
+	segs := segmentText(text)
+	if n > len(segs) {
+		n = len(segs)
+	}
+	return joinSegments(segs[:len(segs)-n]), joinSegments(segs[len(segs)-n:])
+}
+
+// countChars returns the number of chars (grapheme clusters) of a text,
+// the spaces next to chars of scripts written without spaces are not counted
+func countChars(text string) int {
+	return countGraphemes(removeSpacesInNoSpaceScript(text))
+}
+
+// countJoinedChars returns the number of chars of a text made of
+// lines joined with spaces, the joining spaces are not counted
+// (they are not added in scripts written without spaces)
+func countJoinedChars(text string, lines int) int {
+	if hasNoSpaceScript(text) {
+		return countChars(text)
+	}
+	return countChars(text) - lines + 1
+}
+
+// countWords returns the number of words in a text
+// Each CJK character or Thai cluster counts as a word
+func countWords(text string) int {
+	return len(segmentText(text))
+}
+
+// needsSpace returns true if two texts joined need a space between them
+// There is no space next to a char of a script written without spaces
+func needsSpace(str1, str2 string) bool {
+	if str1 == "" || str2 == "" {
+		return true
+	}
+	last, _ := utf8.DecodeLastRuneInString(str1)
+	first, _ := utf8.DecodeRuneInString(str2)
+	return !isNoSpaceScript(last) && !isNoSpaceScript(first)
+}
+
+// removeSpacesInNoSpaceScript removes the spaces next to chars of
+// scripts written without spaces, to compare texts split in lines
+func removeSpacesInNoSpaceScript(text string) string {
+	if !hasNoSpaceScript(text) {
+		return text
+	}
+	runes := []rune(text)
+	out := make([]rune, 0, len(runes))
+	for i, r := range runes {
+		if unicode.IsSpace(r) {
+			if (len(out) > 0 && isNoSpaceScript(out[len(out)-1])) || (i+1 < len(runes) && isNoSpaceScript(runes[i+1])) {
+				continue
+			}
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
+
+// runeWidth returns the width of a rune in a monospace display:
+// 2 for east asian wide and fullwidth chars, 0 for combining marks
+func runeWidth(r rune) int {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Cf):
+		return 0
+	case r >= 0x1100 && r <= 0x115F, // Hangul Jamo
+		r >= 0x2E80 && r <= 0x303E, // CJK radicals, symbols and punctuation
+		r >= 0x3041 && r <= 0x33FF, // Kana, Bopomofo, CJK compatibility
+		r >= 0x3400 && r <= 0x4DBF, // CJK extension A
+		r >= 0x4E00 && r <= 0x9FFF, // CJK unified ideographs
+		r >= 0xA000 && r <= 0xA4CF, // Yi
+		r >= 0xAC00 && r <= 0xD7A3, // Hangul syllables
+		r >= 0xF900 && r <= 0xFAFF, // CJK compatibility ideographs
+		r >= 0xFE30 && r <= 0xFE4F, // CJK compatibility forms
+		r >= 0xFF00 && r <= 0xFF60, // Fullwidth forms
+		r >= 0xFFE0 && r <= 0xFFE6,
+		r >= 0x1F300 && r <= 0x1F64F, // Emoji
+		r >= 0x1F900 && r <= 0x1F9FF,
+		r >= 0x20000 && r <= 0x3FFFD: // CJK extensions B..
+		return 2
+	}
+	return 1
+}
@@ -1,7 +1,6 @@
 package subtitle
 
 import (
-	"fmt"
 	"math"
 	"regexp"
 	"strings"
@@ -42,25 +41,13 @@ func SplitSubtitles(data []byte, atEOF bool) (advance int, token []byte, err err
 }
 
 // prepare a string, clean up, etc.
+// It is the default profile of TextNormalizer
 func prepareString(data string) string {
-	// convert []byte to string
-	text := string(data)
-
-	// Note that \s == [ \t\f\n\r\v]
-
-	// Substitute multiple spaces to single space
-	text = regexp.MustCompile(`\s+`).ReplaceAllString(text, " ")
-	// Change space+punctuation to puntuation alone ('hi ! ya' => 'hi! ya')
-	text = regexp.MustCompile(`\s([,:;!\\?\.\)\]])`).ReplaceAllString(text, "$1")
-	// Change punctuation+char to puntuation+space+char ('hi!ya' => 'hi! ya')
-	text = regexp.MustCompile(`([,:;!\\?\.\)\]])(\S)`).ReplaceAllString(text, "$1 $2")
-	// Delete spaces at the beginning and end of text
-	text = strings.TrimSpace(text)
-
-	return text
+	return TextNormalizer{}.Normalize(data)
 }
 
 // JoinStrings concatenates all the lines using space as separator
+// No space is added next to chars of scripts written without spaces
 func joinStrings(data ...string) string {
 
 	switch len(data) {
@@ -74,7 +61,9 @@ func joinStrings(data ...string) string {
 	text.WriteString(data[0])
 	for _, s := range data[1:] {
 		if s != "" {
-			text.WriteString(" ")
+			if needsSpace(text.String(), s) {
+				text.WriteString(" ")
+			}
 			text.WriteString(s)
 
 		}
@@ -126,10 +115,10 @@ func ClosestFloat(center, first, second float64) bool {
 	}
 }
 
-// Returns the closest string and lenght
+// Returns the closest string and lenght (chars)
 func ClosestNotEmptyString(center float64, str1, str2 string) (string, float64) {
-	len1 := float64(len([]rune(str1)))
-	len2 := float64(len([]rune(str2)))
+	len1 := float64(countChars(str1))
+	len2 := float64(countChars(str2))
 	if ClosestFloat(center, len1, len2) && str1 != "" {
 		return str1, len1 - center
 	}
@@ -146,14 +135,38 @@ func ConcatWithSpace(str1, str2 string) string {
 	return str1 + " " + str2
 }
 
-func PrintStringMaxWidth(s string, width int) string {
+// lineWidth returns the width of a line of text in a monospace
+// display: east asian wide chars take two cells, and a char with
+// combining marks is measured as one (grapheme clusters)
+func lineWidth(s string) int {
+	width := 0
+	for s != "" {
+		n := nextGraphemeCluster(s)
+		width += graphemeWidth(s[:n])
+		s = s[n:]
+	}
+	return width
+}
 
-	lenString := len([]rune(s))
+// PrintStringMaxWidth returns the text padded or cut (with "...")
+// to a display width
+func PrintStringMaxWidth(s string, width int) string {
 
-	// Print the text
-	if lenString > width {
-		return fmt.Sprintf("%-*.*s...", width-3, width-3, s)
-	} else {
-		return fmt.Sprintf("%-*.*s", width, width, s)
+	// Cut the text, by grapheme clusters
+	if lineWidth(s) > width {
+		cut, w := "", 0
+		for _, c := range graphemeClusters(s) {
+			if w+graphemeWidth(c) > width-3 {
+				break
+			}
+			cut += c
+			w += graphemeWidth(c)
+		}
+		s = cut + "..."
+	}
+	// Pad the text
+	if pad := width - lineWidth(s); pad > 0 {
+		s += strings.Repeat(" ", pad)
 	}
+	return s
 }